@@ -2,168 +2,385 @@ package cmdline
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
 	"github.com/serztle/nom/util"
 )
 
-func createNewRecipe(recipe index.Recipe, path, pathName, repoDir string, force, quiet bool) (string, error) {
-	if err := os.MkdirAll(filepath.Dir(pathName), 0700); err != nil {
-		return "", err
-	}
+// newRecipeOptions says how a recipe without a file is filled in.
+type newRecipeOptions struct {
+	// Category whose template pre-fills the recipe.
+	Category string
+	// Wizard asks for the recipe on the terminal instead of opening an editor.
+	Wizard bool
+}
 
+func createNewRecipe(st storage.Store, recipe *index.Recipe, srcPath string, opts newRecipeOptions, force, quiet bool) (string, error) {
 	if !force {
-		if err := util.GuardExists(pathName); err != nil {
+		if err := guardExists(st, recipe.Name); err != nil {
 			return "", err
 		}
 	}
 
 	pathSrc := ""
 
-	if path != "" {
-		pathSrc = filepath.Dir(path)
-		if err := recipe.Parse(path); err != nil {
+	if srcPath != "" {
+		pathSrc = filepath.Dir(srcPath)
+		if err := recipe.Parse(srcPath); err != nil {
+			return "", err
+		}
+	} else if opts.Wizard {
+		recipe.Data.Category = opts.Category
+		if err := runWizard(st, recipe, os.Stdin, os.Stdout); err != nil {
 			return "", err
 		}
+	} else if !quiet {
+		template, ok, err := index.LoadTemplate(st, opts.Category)
+		if err != nil {
+			return "", err
+		} else if ok {
+			recipe.Data = template.Data
+		} else if opts.Category != "" {
+			return "", fmt.Errorf("There is no template for '%s'", opts.Category)
+		}
 
-		if err := recipe.Save(pathName); err != nil {
+		content, err := recipe.String()
+		if err != nil {
 			return "", err
 		}
-	} else {
-		pathSrc = repoDir
-		recipe.Save(pathName)
 
-		if !quiet {
-			if err := util.Edit(pathName); err != nil {
-				return "", err
-			}
+		edited, err := util.EditData([]byte(content))
+		if err != nil {
+			return "", err
+		}
+
+		if err := recipe.Unmarshal(edited, recipe.Name); err != nil {
+			return "", err
 		}
 	}
 
 	return pathSrc, nil
 }
 
-func handleAdd(store *index.Index, name, path string, force, quiet bool, argImages []string) error {
-	repoDir := store.RepoDir()
-	recipeExists := store.RecipeExists(name)
-	pathName, err := filepath.Abs(filepath.Join(repoDir, name))
+// storeImage puts the image `data` originally called `src` into the store
+// and returns where it went. Images already stored are not written again.
+func storeImage(st storage.Store, data []byte, src string) (string, error) {
+	dest := index.ImagePath(data, src)
+	if st.Exists(dest) {
+		fmt.Printf("Info: Image '%s' is already stored as '%s'.\n", src, dest)
+		return dest, nil
+	}
+
+	return dest, st.Write(dest, data)
+}
+
+// imageConfig returns how images are normalized, or nil to keep them as they are.
+func imageConfig(store *index.Index, keepOriginal bool) (*index.ImageConfig, error) {
+	if keepOriginal {
+		return nil, nil
+	}
+
+	config := index.NewConfig(store.Store())
+	if err := config.Parse(); err != nil {
+		return nil, err
+	}
+
+	return &config.Images, nil
+}
+
+// normalizeImage applies `config` to the image `data` originally called
+// `src` and returns the result with its new name. Without `config` the
+// data is kept as it is, as long as it is not plainly something else.
+func normalizeImage(config *index.ImageConfig, data []byte, src string) ([]byte, string, error) {
+	if config == nil {
+		if err := util.CheckImage(data); err != nil {
+			return nil, "", fmt.Errorf("Image '%s' is not usable (%v)", src, err)
+		}
+
+		return data, src, nil
+	}
+
+	normalized, ext, err := util.NormalizeImage(data, config.MaxSize, config.Format, config.Quality)
+	if err != nil {
+		return nil, "", fmt.Errorf("Image '%s' is not usable (%v)", src, err)
+	}
+
+	return normalized, strings.TrimSuffix(src, filepath.Ext(src)) + ext, nil
+}
+
+// importImage copies the image at `src` (outside of the store) into the
+// store, normalized according to `config`.
+func importImage(st storage.Store, src string, config *index.ImageConfig) (string, error) {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return "", fmt.Errorf("Reading image '%s' failed (%v)", src, err)
+	}
+
+	data, name, err := normalizeImage(config, data, src)
+	if err != nil {
+		return "", err
+	}
+
+	return storeImage(st, data, name)
+}
+
+func handleAdd(store *index.Index, name, srcPath string, opts newRecipeOptions, force, quiet, keepOriginal bool, argImages []string) error {
+	st := store.Store()
+	config, err := imageConfig(store, keepOriginal)
 	if err != nil {
 		return err
 	}
 
+	recipeExists := store.RecipeExists(name)
+
 	pathSrc := ""
 
-	recipe := index.Recipe{}
+	recipe := index.NewRecipe(name)
 	if !recipeExists {
-		pathSrc, err = createNewRecipe(recipe, path, pathName, repoDir, force, quiet)
+		var err error
+		pathSrc, err = createNewRecipe(st, &recipe, srcPath, opts, force, quiet)
 		if err != nil {
 			return err
 		}
-	} else if path != "" {
+	} else if srcPath != "" {
 		return fmt.Errorf("Recipe '%s' already exists", name)
-	}
-
-	git := util.NewGit(repoDir)
-	if err := recipe.Parse(pathName); err != nil {
+	} else if err := recipe.Load(st); err != nil {
 		return err
 	}
 
-	var images []string
+	old := recipe
+	if !recipeExists {
+		if err := warnSimilar(store, &recipe); err != nil {
+			return err
+		}
+	}
 
-	imagePath := filepath.Join(
-		repoDir,
-		".images",
-		name,
-	)
+	return st.WithTransaction(func() error {
+		var images []string
 
-	if err := os.MkdirAll(imagePath, 0700); err != nil {
-		return err
-	}
+		if !recipeExists {
+			for _, image := range recipe.Data.Images {
+				imagePathDest, err := importImage(st, filepath.Join(pathSrc, image), config)
+				if err != nil {
+					return err
+				}
 
-	if !recipeExists {
-		for _, image := range recipe.Data.Images {
-			imagePathSrc := filepath.Join(
-				pathSrc,
-				image,
-			)
-			imagePathDest := filepath.Join(
-				imagePath,
-				filepath.Base(image),
-			)
-
-			if err := util.CopyFile(imagePathSrc, imagePathDest); err != nil {
-				return err
+				if !containsString(images, imagePathDest) {
+					images = append(images, imagePathDest)
+				}
 			}
+		} else {
+			images = append(images, recipe.Data.Images...)
+		}
 
-			relPath, err := filepath.Rel(repoDir, imagePathDest)
+		for _, argImage := range argImages {
+			imagePathDest, err := importImage(st, argImage, config)
 			if err != nil {
 				return err
 			}
 
-			images = append(images, relPath)
+			if !containsString(images, imagePathDest) {
+				images = append(images, imagePathDest)
+			}
+		}
+
+		recipe.Data.Images = images
+
+		store.RecipeAdd(name)
+		if err := recipe.Save(st); err != nil {
+			return err
+		}
+
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "add", messageData{Name: name})
+		if recipeExists {
+			message, err = commitMessage(store, "image", messageData{
+				Name:    name,
+				Summary: recipeSummary(&old, &recipe),
+			})
 		}
-	} else {
-		images = append(images, recipe.Data.Images...)
-	}
 
-	for _, argImage := range argImages {
-		imagePathDest := filepath.Join(
-			imagePath,
-			filepath.Base(argImage),
-		)
+		if err != nil {
+			return err
+		}
 
-		if err := util.CopyFile(argImage, imagePathDest); err != nil {
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No new things here. Nothing to do.")
+		} else if err != nil {
 			return err
 		}
 
-		relPath, err := filepath.Rel(repoDir, imagePathDest)
+		return nil
+	})
+}
+
+// bulkRecipe is a recipe read for a bulk add, with its images already loaded
+// and normalized, so that only writing to the store is left.
+type bulkRecipe struct {
+	path   string
+	recipe index.Recipe
+	images []bulkImage
+}
+
+// bulkImage is an image of a bulkRecipe and the name it is stored by.
+type bulkImage struct {
+	data []byte
+	name string
+}
+
+// bulkPaths returns all yaml files below `dir`, sorted.
+func bulkPaths(dir string) ([]string, error) {
+	paths := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !recipe.ImageExists(relPath) {
-			images = append(images, relPath)
+		if info.IsDir() && path != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if !info.IsDir() && (ext == ".yml" || ext == ".yaml") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	return paths, err
+}
+
+// readBulkRecipe reads the recipe at `path` below `dir`. Its name is the
+// file name; the subdirectories become the category unless it has one.
+func readBulkRecipe(store *index.Index, config *index.ImageConfig, dir, path string, force bool) (*bulkRecipe, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	if !force {
+		if store.RecipeExists(name) {
+			return nil, fmt.Errorf("Recipe '%s' already exists", name)
+		}
+
+		if err := guardExists(store.Store(), name); err != nil {
+			return nil, err
+		}
+	}
+
+	bulk := &bulkRecipe{path: path, recipe: index.NewRecipe(name)}
+	if err := bulk.recipe.Parse(path); err != nil {
+		return nil, err
+	}
+
+	if category := filepath.ToSlash(filepath.Dir(rel)); bulk.recipe.Data.Category == "" && category != "." {
+		bulk.recipe.Data.Category = category
+	}
+
+	for _, image := range bulk.recipe.Data.Images {
+		src := filepath.Join(filepath.Dir(path), image)
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("Reading image '%s' failed (%v)", src, err)
+		}
+
+		data, imageName, err := normalizeImage(config, data, image)
+		if err != nil {
+			return nil, err
 		}
+
+		bulk.images = append(bulk.images, bulkImage{data: data, name: imageName})
 	}
 
-	recipe.Data.Images = images
+	return bulk, nil
+}
+
+// handleAddBulk adds all yaml recipes below `dir` in one commit. Files that
+// fail are reported and left out, unless `strict` is set: then nothing is added.
+func handleAddBulk(store *index.Index, dir string, force, strict, keepOriginal bool) error {
+	paths, err := bulkPaths(dir)
+	if err != nil {
+		return err
+	}
+
+	config, err := imageConfig(store, keepOriginal)
+	if err != nil {
+		return err
+	}
+
+	st := store.Store()
+	return st.WithTransaction(func() error {
+		added, failed := []string{}, 0
+		for _, path := range paths {
+			bulk, err := readBulkRecipe(store, config, dir, path, force)
+			if err == nil && containsString(added, bulk.recipe.Name) {
+				err = fmt.Errorf("Recipe '%s' is given more than once", bulk.recipe.Name)
+			}
+
+			if err != nil && strict {
+				return fmt.Errorf("Adding '%s' failed (%v)", path, err)
+			} else if err != nil {
+				fmt.Printf("Warning: Could not add '%s' (%v)\n", path, err)
+				failed++
+				continue
+			}
 
-	store.RecipeAdd(name)
-	recipe.Save(pathName)
-	store.Save()
+			images := []string{}
+			for _, image := range bulk.images {
+				dest, err := storeImage(st, image.data, image.name)
+				if err != nil {
+					return err
+				}
 
-	git.WithTransaction(func() error {
-		for _, image := range images {
-			if err := git.Add(image); err != nil {
+				if !containsString(images, dest) {
+					images = append(images, dest)
+				}
+			}
+
+			bulk.recipe.Data.Images = images
+			if err := bulk.recipe.Save(st); err != nil {
 				return err
 			}
+
+			store.RecipeAdd(bulk.recipe.Name)
+			added = append(added, bulk.recipe.Name)
 		}
 
-		if err := git.Add(store.Filename()); err != nil {
-			return err
+		if len(added) == 0 {
+			return fmt.Errorf("No recipe could be added from '%s'", dir)
 		}
 
-		if err := git.Add(pathName); err != nil {
+		if err := store.Save(); err != nil {
 			return err
 		}
 
-		if git.HasChanges(true) {
-			message := "New recipe added"
-			if recipeExists {
-				message = "Image added to recipe"
-			}
+		data := messageData{Name: added[0]}
+		if len(added) > 1 {
+			data.Name = fmt.Sprintf("%d recipes", len(added))
+		}
 
-			if err := git.Commit(message); err != nil {
-				return err
-			}
-		} else {
+		message, err := commitMessage(store, "add", data)
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
 			fmt.Println("Info: No new things here. Nothing to do.")
+		} else if err != nil {
+			return err
 		}
 
+		fmt.Printf("Info: Added %d recipes, %d failed.\n", len(added), failed)
 		return nil
 	})
-
-	return nil
 }
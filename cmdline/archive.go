@@ -0,0 +1,47 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// handleArchive archives the recipe `name`, or brings it back if
+// `archived` is false. Its file, images and collections are kept.
+func handleArchive(store *index.Index, name string, archived bool) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	if store.RecipeArchived(name) == archived {
+		fmt.Println("Info: No changes. Nothing to do.")
+		return nil
+	}
+
+	operation := "archive"
+	if !archived {
+		operation = "unarchive"
+	}
+
+	st := store.Store()
+	return st.WithTransaction(func() error {
+		store.RecipeArchive(name, archived)
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, operation, messageData{Name: name})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,152 @@
+package cmdline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// filterCollection narrows `store` down to the recipes of `collection`.
+// An empty `collection` means all recipes.
+func filterCollection(store *index.Index, collection string) (*index.Index, error) {
+	if collection == "" {
+		return store, nil
+	}
+
+	collections := index.NewCollections(store.Store())
+	if err := collections.Parse(); err != nil {
+		return nil, err
+	}
+
+	return collections.Filter(store, collection)
+}
+
+func withCollections(store *index.Index, operation, name string, fn func(collections *index.Collections) error) error {
+	collections := index.NewCollections(store.Store())
+	if err := collections.Parse(); err != nil {
+		return err
+	}
+
+	before := append([]string{}, collections.Sets[name]...)
+
+	return store.Store().WithTransaction(func() error {
+		if err := fn(collections); err != nil {
+			return err
+		}
+
+		if err := collections.Save(); err != nil {
+			return err
+		}
+
+		summary := index.Summary([]index.Change{{
+			Field: "recipes",
+			List:  true,
+			Lines: index.DiffLists(before, collections.Sets[name]),
+		}})
+
+		message, err := commitMessage(store, operation, messageData{Name: name, Summary: summary})
+		if err != nil {
+			return err
+		}
+
+		if err := store.Store().Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func handleCollectionCreate(store *index.Index, name string) error {
+	return withCollections(store, "collection-create", name, func(collections *index.Collections) error {
+		if collections.Exists(name) {
+			return fmt.Errorf("Collection '%s' already exists", name)
+		}
+
+		if strings.Contains(name, "/") {
+			return fmt.Errorf("Collection names may not contain '/'")
+		}
+
+		collections.Create(name)
+		return nil
+	})
+}
+
+func handleCollectionAdd(store *index.Index, name string, recipes []string) error {
+	return withCollections(store, "collection-add", name, func(collections *index.Collections) error {
+		if !collections.Exists(name) {
+			return fmt.Errorf("No collection found with the name '%s'", name)
+		}
+
+		for _, recipe := range recipes {
+			if !store.RecipeExists(recipe) {
+				return fmt.Errorf("No Recipe found with the name '%s'", recipe)
+			}
+
+			collections.RecipeAdd(name, recipe)
+		}
+
+		return nil
+	})
+}
+
+func handleCollectionRemove(store *index.Index, name string, recipes []string) error {
+	operation := "collection-rm"
+	if len(recipes) == 0 {
+		operation = "collection-delete"
+	}
+
+	return withCollections(store, operation, name, func(collections *index.Collections) error {
+		if !collections.Exists(name) {
+			return fmt.Errorf("No collection found with the name '%s'", name)
+		}
+
+		if len(recipes) == 0 {
+			collections.Delete(name)
+			return nil
+		}
+
+		for _, recipe := range recipes {
+			collections.RecipeRemove(name, recipe)
+		}
+
+		return nil
+	})
+}
+
+func handleCollectionList(store *index.Index, name string) error {
+	collections := index.NewCollections(store.Store())
+	if err := collections.Parse(); err != nil {
+		return err
+	}
+
+	if name == "" {
+		for _, collection := range collections.Names() {
+			fmt.Printf("%s (%d recipes)\n", collection, len(collections.Sets[collection]))
+		}
+
+		return nil
+	}
+
+	filtered, err := collections.Filter(store, name)
+	if err != nil {
+		return err
+	}
+
+	recipes := []string{}
+	for recipe := range filtered.Recipes {
+		recipes = append(recipes, recipe)
+	}
+
+	sort.Strings(recipes)
+	for _, recipe := range recipes {
+		fmt.Println(recipe)
+	}
+
+	return nil
+}
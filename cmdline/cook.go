@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
 	"github.com/serztle/nom/util"
 )
 
@@ -33,8 +33,8 @@ func createTempCookFile(ingredients []string, persons int) (string, error) {
 }
 
 func handleCook(store *index.Index, name string, persons int) error {
-	recipe := index.Recipe{}
-	if err := recipe.Parse(filepath.Join(store.RepoDir(), name)); err != nil {
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(store.Store()); err != nil {
 		return err
 	}
 
@@ -87,3 +87,44 @@ func handleCook(store *index.Index, name string, persons int) error {
 	fmt.Println("")
 	return nil
 }
+
+// handleCooked remembers that `name` was cooked on `dateStr` (today if empty).
+func handleCooked(store *index.Index, name, dateStr string) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	date := time.Now()
+	if dateStr != "" {
+		var err error
+		if date, err = time.Parse(index.CookedFormat, dateStr); err != nil {
+			return fmt.Errorf("Dates look like %s, not '%s'", index.CookedFormat, dateStr)
+		}
+	}
+
+	st := store.Store()
+	cooked := index.NewCooked(st)
+	if err := cooked.Parse(); err != nil {
+		return err
+	}
+
+	return st.WithTransaction(func() error {
+		cooked.Mark(name, date)
+		if err := cooked.Save(); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "cook", messageData{Name: name})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
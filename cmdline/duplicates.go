@@ -0,0 +1,155 @@
+package cmdline
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/serztle/nom/index"
+)
+
+// warnSimilar tells about recipes in the index that look like `recipe`.
+func warnSimilar(store *index.Index, recipe *index.Recipe) error {
+	similar, err := store.SimilarRecipes(recipe, index.DuplicateThreshold)
+	if err != nil {
+		return err
+	}
+
+	for _, duplicate := range similar {
+		fmt.Printf("Warning: '%s' looks like '%s' (%.0f%% similar); see `nom duplicates`.\n",
+			duplicate.A, duplicate.B, duplicate.Score*100)
+	}
+
+	return nil
+}
+
+// mergeDuplicate merges the recipe `drop` into `keep` and removes it,
+// also from the collections. It does not commit.
+func mergeDuplicate(store *index.Index, keep, drop string) error {
+	st := store.Store()
+	kept, dropped := index.NewRecipe(keep), index.NewRecipe(drop)
+	if err := kept.Load(st); err != nil {
+		return err
+	}
+
+	if err := dropped.Load(st); err != nil {
+		return err
+	}
+
+	if err := kept.Absorb(&dropped); err != nil {
+		return err
+	}
+
+	if err := kept.Save(st); err != nil {
+		return err
+	}
+
+	store.RecipeRemove(drop)
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	collections := index.NewCollections(st)
+	if err := collections.Parse(); err != nil {
+		return err
+	}
+
+	if st.Exists(collections.Filename()) {
+		for _, name := range collections.Names() {
+			if collections.Contains(name, drop) {
+				collections.RecipeAdd(name, keep)
+			}
+		}
+
+		collections.RecipeForget(drop)
+		if err := collections.Save(); err != nil {
+			return err
+		}
+	}
+
+	cooked := index.NewCooked(st)
+	if err := cooked.Parse(); err != nil {
+		return err
+	}
+
+	if st.Exists(cooked.Filename()) {
+		// The kept recipe was cooked whenever one of them was.
+		droppedDate, droppedCooked := cooked.Last(drop)
+		keptDate, keptCooked := cooked.Last(keep)
+		if droppedCooked && (!keptCooked || droppedDate.After(keptDate)) {
+			cooked.Mark(keep, droppedDate)
+		}
+
+		cooked.RecipeForget(drop)
+		if err := cooked.Save(); err != nil {
+			return err
+		}
+	}
+
+	return st.Delete(drop)
+}
+
+func handleDuplicates(store *index.Index, threshold float64, merge bool, in io.Reader) error {
+	duplicates, err := store.Duplicates(threshold)
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		fmt.Println("Info: No duplicates found.")
+		return nil
+	}
+
+	if !merge {
+		for _, duplicate := range duplicates {
+			fmt.Printf("%3.0f%% %s %s\n", duplicate.Score*100, duplicate.A, duplicate.B)
+		}
+
+		return nil
+	}
+
+	w := newWizard(in, os.Stdout)
+	checkAnswer := func(answer string) error {
+		if answer != "a" && answer != "b" && answer != "n" {
+			return fmt.Errorf("Please answer a, b or n")
+		}
+		return nil
+	}
+
+	for _, duplicate := range duplicates {
+		if !store.RecipeExists(duplicate.A) || !store.RecipeExists(duplicate.B) {
+			// One of them was merged away already.
+			continue
+		}
+
+		fmt.Printf("%3.0f%% a: %s, b: %s\n", duplicate.Score*100, duplicate.A, duplicate.B)
+		keep, drop := duplicate.A, duplicate.B
+		switch w.ask("Merge and keep a, keep b, or don't merge (a/b/n)", "n", checkAnswer) {
+		case "b":
+			keep, drop = drop, keep
+		case "n":
+			continue
+		}
+
+		st := store.Store()
+		err := st.WithTransaction(func() error {
+			if err := mergeDuplicate(store, keep, drop); err != nil {
+				return err
+			}
+
+			message, err := commitMessage(store, "merge", messageData{Name: keep, NewName: drop})
+			if err != nil {
+				return err
+			}
+
+			return st.Commit(message)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Info: Merged '%s' into '%s'.\n", drop, keep)
+	}
+
+	return nil
+}
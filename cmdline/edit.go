@@ -1,22 +1,171 @@
 package cmdline
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
 	"github.com/serztle/nom/util"
 )
 
-func handleEdit(store *index.Index, name string) error {
-	pathName := filepath.Join(store.RepoDir(), name)
+// editComment starts the lines nom adds to a recipe being edited.
+const editComment = "# nom: "
+
+// stripEditComments removes the lines added by nom from `content`.
+func stripEditComments(content []byte) []byte {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	kept := [][]byte{}
+	for _, line := range lines {
+		if !bytes.HasPrefix(line, []byte(editComment)) {
+			kept = append(kept, line)
+		}
+	}
+
+	return bytes.Join(kept, nil)
+}
+
+// editRecipe lets the user edit `content` in a temporary file until it is
+// a valid recipe that passes `check`, showing what is wrong as a comment on
+// top. It returns nil if the user gives up by emptying the file or saving
+// it unchanged after an error.
+func editRecipe(name string, content []byte, check func(recipe *index.Recipe) error) ([]byte, error) {
+	failed := false
+	for {
+		edited, err := util.EditData(content)
+		if err != nil {
+			return nil, err
+		}
+
+		edited = stripEditComments(edited)
+		if len(bytes.TrimSpace(edited)) == 0 {
+			return nil, nil
+		}
+
+		if failed && bytes.Equal(edited, stripEditComments(content)) {
+			return nil, nil
+		}
+
+		recipe := index.NewRecipe(name)
+		err = recipe.UnmarshalStrict(edited, name)
+		if err == nil {
+			err = check(&recipe)
+		}
+
+		if err == nil {
+			return edited, nil
+		}
+
+		fmt.Printf("Warning: %v\n", err)
+		failed = true
 
+		comment := &bytes.Buffer{}
+		for _, line := range strings.Split(err.Error(), "\n") {
+			comment.WriteString(editComment + line + "\n")
+		}
+		comment.WriteString(editComment + "Fix it, or empty the file or save it unchanged to abort.\n")
+		content = append(comment.Bytes(), edited...)
+	}
+}
+
+// externalImage returns the file outside of the store that the image entry
+// `image` refers to, or "" if the image is in the store already. Relative
+// paths are relative to the repository, not to the directory nom runs in.
+func externalImage(st storage.Store, image string) (string, error) {
+	if strings.HasPrefix(image, index.ImagesDir+"/") && st.Exists(image) {
+		return "", nil
+	}
+
+	path := image
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		path = filepath.Join(home, path[2:])
+	} else if !filepath.IsAbs(path) {
+		fs, ok := st.(*storage.FS)
+		if !ok {
+			return "", fmt.Errorf("Image '%s' needs an absolute path", image)
+		}
+
+		path = filepath.Join(fs.Dir(), path)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", fmt.Errorf("Image '%s' does not exist", image)
+	}
+
+	return path, nil
+}
+
+// checkImages makes sure every image of `recipe` either is in the store or can be added.
+func checkImages(st storage.Store, recipe *index.Recipe) error {
+	for _, image := range recipe.Data.Images {
+		if _, err := externalImage(st, image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importEditedImages copies the images of `recipe` that are not in the
+// store yet into it and refers to them by their new path, like add does.
+// It returns whether any image was imported.
+func importEditedImages(st storage.Store, recipe *index.Recipe, config *index.ImageConfig) (bool, error) {
+	imported := false
+	images := []string{}
+	for _, image := range recipe.Data.Images {
+		path, err := externalImage(st, image)
+		if err != nil {
+			return false, err
+		}
+
+		dest := image
+		if path != "" {
+			if dest, err = importImage(st, path, config); err != nil {
+				return false, err
+			}
+
+			if recipe.Data.Cover == image {
+				recipe.Data.Cover = dest
+			}
+
+			if caption := recipe.Caption(image); caption != "" {
+				recipe.SetCaption(image, "")
+				recipe.SetCaption(dest, caption)
+			}
+
+			imported = true
+		}
+
+		if !containsString(images, dest) {
+			images = append(images, dest)
+		}
+	}
+
+	recipe.Data.Images = images
+	return imported, nil
+}
+
+func handleEdit(store *index.Index, name string) error {
 	if !store.RecipeExists(name) {
 		return fmt.Errorf("Info: No Recipe found with the name '%s'\n", name)
 	}
 
-	recipe := index.Recipe{}
-	if err := recipe.Parse(pathName); err != nil {
+	st := store.Store()
+	content, err := st.Read(name)
+	if err != nil {
+		return err
+	}
+
+	recipe := index.NewRecipe(name)
+	if err := recipe.Unmarshal(content, name); err != nil {
 		return err
 	}
 
@@ -25,40 +174,71 @@ func handleEdit(store *index.Index, name string) error {
 		images[image] = true
 	}
 
-	if err := util.Edit(pathName); err != nil {
+	config, err := imageConfig(store, false)
+	if err != nil {
 		return err
 	}
 
-	if err := recipe.Parse(pathName); err != nil {
+	edited, err := editRecipe(name, content, func(recipe *index.Recipe) error {
+		return checkImages(st, recipe)
+	})
+	if err != nil {
 		return err
+	} else if edited == nil {
+		fmt.Println("Info: Edit aborted, nothing changed.")
+		return nil
 	}
 
-	git := util.NewGit(store.RepoDir())
-	for _, image := range recipe.Data.Images {
-		delete(images, image)
+	old := recipe
+	recipe = index.NewRecipe(name)
+	if err := recipe.Unmarshal(edited, name); err != nil {
+		return err
 	}
 
-	git.WithTransaction(func() error {
+	return st.WithTransaction(func() error {
+		imported, err := importEditedImages(st, &recipe, config)
+		if err != nil {
+			return err
+		}
+
+		// Keep the file as it was written, unless image paths changed.
+		if imported {
+			err = recipe.Save(st)
+		} else {
+			err = st.Write(name, edited)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, image := range recipe.Data.Images {
+			delete(images, image)
+		}
+
+		removedImages := []string{}
 		for image := range images {
-			if err := git.Remove(image); err != nil {
-				return err
-			}
+			removedImages = append(removedImages, image)
 		}
 
-		if err := git.Add(pathName); err != nil {
+		if _, err := removeUnreferenced(store, removedImages); err != nil {
 			return err
 		}
 
-		if git.HasChanges(true) {
-			if err := git.Commit("Recipe changed"); err != nil {
-				return err
-			}
-		} else {
+		message, err := commitMessage(store, "edit", messageData{
+			Name:    name,
+			Summary: recipeSummary(&old, &recipe),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
 			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
 		}
 
 		return nil
 	})
-
-	return nil
 }
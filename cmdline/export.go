@@ -0,0 +1,62 @@
+package cmdline
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/serztle/nom/exporter"
+	"github.com/serztle/nom/index"
+)
+
+func handleExport(store *index.Index, format string, names []string, outDir string) error {
+	exportFormat, ok := exporter.Formats[format]
+	if !ok {
+		return fmt.Errorf("Unknown format '%s'; known are %v", format, exporter.FormatNames())
+	}
+
+	if outDir == "" {
+		return fmt.Errorf("Please give the directory to export to with --output")
+	}
+
+	if len(names) == 0 {
+		for name := range store.Recipes {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+	}
+
+	recipes := []index.Recipe{}
+	imageOwners := make(map[string]string)
+	for _, name := range names {
+		if !store.RecipeExists(name) {
+			return fmt.Errorf("No Recipe found with the name '%s'", name)
+		}
+
+		recipe := index.NewRecipe(name)
+		if err := recipe.Load(store.Store()); err != nil {
+			return err
+		}
+
+		// Recipes with names like "a" and "a.2" could write the same image file.
+		for _, path := range exporter.ImagePaths(&recipe, outDir) {
+			if owner, ok := imageOwners[path]; ok && owner != name {
+				return fmt.Errorf("The images of '%s' and '%s' would both be written to '%s'", owner, name, path)
+			}
+
+			imageOwners[path] = name
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	for idx := range recipes {
+		recipe := &recipes[idx]
+		if err := exporter.Export(store.Store(), exportFormat, recipe, outDir); err != nil {
+			return fmt.Errorf("Exporting '%s' failed (%v)", recipe.Name, err)
+		}
+	}
+
+	fmt.Printf("Info: Exported %d recipes to '%s'.\n", len(names), outDir)
+	return nil
+}
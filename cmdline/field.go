@@ -0,0 +1,98 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+func handleGet(store *index.Index, name, path string) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(store.Store()); err != nil {
+		return err
+	}
+
+	values, err := recipe.Get(path)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		fmt.Println(value)
+	}
+
+	return nil
+}
+
+func handleSet(store *index.Index, name, path string, values []string) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	st := store.Store()
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(st); err != nil {
+		return err
+	}
+
+	// Loaded again, as changing the recipe changes some of its values in place.
+	old := index.NewRecipe(name)
+	if err := old.Load(st); err != nil {
+		return err
+	}
+
+	if err := recipe.Set(path, values); err != nil {
+		return err
+	}
+
+	if err := checkImages(st, &recipe); err != nil {
+		return err
+	}
+
+	config, err := imageConfig(store, false)
+	if err != nil {
+		return err
+	}
+
+	return st.WithTransaction(func() error {
+		if _, err := importEditedImages(st, &recipe, config); err != nil {
+			return err
+		}
+
+		if err := recipe.Save(st); err != nil {
+			return err
+		}
+
+		dropped := []string{}
+		for _, image := range old.Data.Images {
+			if !containsString(recipe.Data.Images, image) {
+				dropped = append(dropped, image)
+			}
+		}
+
+		if _, err := removeUnreferenced(store, dropped); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "edit", messageData{
+			Name:    name,
+			Summary: recipeSummary(&old, &recipe),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
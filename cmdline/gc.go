@@ -0,0 +1,81 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// removeUnreferenced deletes all `images` that no recipe in the index uses anymore.
+// Images can be shared between recipes, so they may not be deleted blindly.
+func removeUnreferenced(store *index.Index, images []string) ([]string, error) {
+	referenced, err := store.ReferencedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for _, image := range images {
+		if referenced[image] || !store.Store().Exists(image) {
+			continue
+		}
+
+		if err := store.Store().Delete(image); err != nil {
+			return nil, err
+		}
+
+		removed = append(removed, image)
+	}
+
+	return removed, nil
+}
+
+func handleGC(store *index.Index, dryRun bool) error {
+	st := store.Store()
+	images, err := st.List(index.ImagesDir)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		referenced, err := store.ReferencedImages()
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			if !referenced[image] {
+				fmt.Printf("Would remove %s\n", image)
+			}
+		}
+
+		return nil
+	}
+
+	return st.WithTransaction(func() error {
+		removed, err := removeUnreferenced(store, images)
+		if err != nil {
+			return err
+		}
+
+		for _, image := range removed {
+			fmt.Printf("Removed %s\n", image)
+		}
+
+		message, err := commitMessage(store, "gc", messageData{
+			Summary: fmt.Sprintf("-%d images", len(removed)),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No unreferenced images. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
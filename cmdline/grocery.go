@@ -3,13 +3,23 @@ package cmdline
 import (
 	"fmt"
 	"io/ioutil"
-	"path/filepath"
 
 	"github.com/serztle/nom/index"
 	"gopkg.in/yaml.v2"
 )
 
-func handleGrocery(store *index.Index, names, plans []string, persons int) error {
+func handleGrocery(store *index.Index, names, plans, collections []string, persons int) error {
+	for _, collection := range collections {
+		filtered, err := filterCollection(store, collection)
+		if err != nil {
+			return err
+		}
+
+		for name := range filtered.Recipes {
+			names = append(names, name)
+		}
+	}
+
 	dateToRecipe := make(map[string]string)
 	for _, plan := range plans {
 		content, err := ioutil.ReadFile(plan)
@@ -30,9 +40,8 @@ func handleGrocery(store *index.Index, names, plans []string, persons int) error
 
 	ingredients := make(map[string]index.Range)
 	for _, name := range names {
-		recipe := index.Recipe{}
-
-		if err := recipe.Parse(filepath.Join(store.RepoDir(), name)); err != nil {
+		recipe := index.NewRecipe(name)
+		if err := recipe.Load(store.Store()); err != nil {
 			return err
 		}
 
@@ -0,0 +1,108 @@
+package cmdline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+func addExample(t *testing.T, store *index.Index, name string) index.Recipe {
+	t.Helper()
+
+	if err := handleAdd(store, name, "../examples/"+name+".yml", newRecipeOptions{}, false, true, true, nil); err != nil {
+		t.Fatalf("Adding %s failed: %v", name, err)
+	}
+
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(store.Store()); err != nil {
+		t.Fatalf("Loading %s failed: %v", name, err)
+	}
+
+	return recipe
+}
+
+func TestAddAndRemove(t *testing.T) {
+	st := storage.NewMemory()
+	store := index.NewIndex(st)
+
+	// Gulasch and filet wellington share their images.
+	gulasch := addExample(t, store, "gulasch")
+	addExample(t, store, "filet_wellington")
+
+	if len(gulasch.Data.Images) != 2 {
+		t.Fatalf("Expected 2 images, got %v", gulasch.Data.Images)
+	}
+
+	for _, image := range gulasch.Data.Images {
+		if !strings.HasPrefix(image, index.ImagesDir+"/") || !st.Exists(image) {
+			t.Errorf("Image %s was not stored", image)
+		}
+	}
+
+	if len(st.Commits) != 2 || !strings.HasPrefix(st.Commits[0].Message, "gulasch: added") {
+		t.Fatalf("Unexpected commits after adding: %v", st.Commits)
+	}
+
+	if err := handleRemove(store, "gulasch"); err != nil {
+		t.Fatalf("Removing gulasch failed: %v", err)
+	}
+
+	if st.Exists("gulasch") || store.RecipeExists("gulasch") {
+		t.Errorf("gulasch is still there after removing it")
+	}
+
+	for _, image := range gulasch.Data.Images {
+		if !st.Exists(image) {
+			t.Errorf("Image %s is still used but was removed", image)
+		}
+	}
+
+	if err := handleRemove(store, "filet_wellington"); err != nil {
+		t.Fatalf("Removing filet_wellington failed: %v", err)
+	}
+
+	for _, image := range gulasch.Data.Images {
+		if st.Exists(image) {
+			t.Errorf("Image %s is not used anymore but was kept", image)
+		}
+	}
+
+	if len(st.Commits) != 4 || !strings.HasPrefix(st.Commits[3].Message, "filet_wellington: removed") {
+		t.Errorf("Unexpected commits after removing: %v", st.Commits)
+	}
+}
+
+func TestRemoveUnreferenced(t *testing.T) {
+	st := storage.NewMemory()
+	store := index.NewIndex(st)
+
+	recipe := index.NewRecipe("soup")
+	recipe.Data.Name = "Soup"
+	recipe.Data.Images = []string{".images/aa/used.jpg"}
+	store.RecipeAdd("soup")
+	if err := recipe.Save(st); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, image := range []string{".images/aa/used.jpg", ".images/bb/unused.jpg"} {
+		if err := st.Write(image, []byte("image")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images := []string{".images/aa/used.jpg", ".images/bb/unused.jpg", ".images/cc/missing.jpg"}
+	removed, err := removeUnreferenced(store, images)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(removed) != 1 || removed[0] != ".images/bb/unused.jpg" {
+		t.Errorf("Expected only the unused image to be removed, got %v", removed)
+	}
+
+	if !st.Exists(".images/aa/used.jpg") || st.Exists(".images/bb/unused.jpg") {
+		t.Errorf("Wrong images left in the store: %v", st.Commits)
+	}
+}
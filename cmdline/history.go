@@ -0,0 +1,195 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+func storeHistory(store *index.Index) (storage.History, error) {
+	history, ok := store.Store().(storage.History)
+	if !ok {
+		return nil, fmt.Errorf("This store does not keep any history")
+	}
+
+	return history, nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+
+	return hash
+}
+
+// previousRevision returns the latest revision in which `name` differs from
+// its current state. For removed recipes this is the last version before removal.
+func previousRevision(history storage.History, name string) (string, error) {
+	entries, err := history.Log(name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) < 2 {
+		return "", fmt.Errorf("No earlier version of '%s' found", name)
+	}
+
+	return entries[1].Hash, nil
+}
+
+func loadRevision(history storage.History, name, rev string) (*index.Recipe, []byte, error) {
+	content, err := history.ReadAt(rev, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Recipe '%s' does not exist at revision '%s'", name, rev)
+	}
+
+	recipe := index.NewRecipe(name)
+	if err := recipe.Unmarshal(content, fmt.Sprintf("%s@%s", name, rev)); err != nil {
+		return nil, nil, err
+	}
+
+	return &recipe, content, nil
+}
+
+func handleLog(store *index.Index, name string) error {
+	history, err := storeHistory(store)
+	if err != nil {
+		return err
+	}
+
+	entries, err := history.Log(name)
+	if err != nil {
+		return err
+	}
+
+	if name != "" && len(entries) == 0 {
+		return fmt.Errorf("Info: No history found for '%s'", name)
+	}
+
+	for _, entry := range entries {
+		fmt.Printf(
+			"%s %s %s\n",
+			shortHash(entry.Hash),
+			entry.Date.Format("2006-01-02 15:04"),
+			entry.Message,
+		)
+	}
+
+	return nil
+}
+
+func handleDiff(store *index.Index, name, rev string) error {
+	history, err := storeHistory(store)
+	if err != nil {
+		return err
+	}
+
+	if rev == "" {
+		if rev, err = previousRevision(history, name); err != nil {
+			return err
+		}
+	}
+
+	old, _, err := loadRevision(history, name, rev)
+	if err != nil {
+		return err
+	}
+
+	current := index.NewRecipe(name)
+	if store.RecipeExists(name) {
+		if err := current.Load(store.Store()); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Info: Recipe '%s' was removed.\n", name)
+	}
+
+	changes := index.Diff(old, &current)
+	if len(changes) == 0 {
+		fmt.Println("Info: No differences.")
+	}
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	return nil
+}
+
+func handleRestore(store *index.Index, name, rev string) error {
+	history, err := storeHistory(store)
+	if err != nil {
+		return err
+	}
+
+	if rev == "" {
+		if rev, err = previousRevision(history, name); err != nil {
+			return err
+		}
+	}
+
+	old, content, err := loadRevision(history, name, rev)
+	if err != nil {
+		return err
+	}
+
+	st := store.Store()
+	return st.WithTransaction(func() error {
+		dropped := []string{}
+		if store.RecipeExists(name) {
+			current := index.NewRecipe(name)
+			if err := current.Load(st); err != nil {
+				return err
+			}
+
+			for _, image := range current.Data.Images {
+				if !old.ImageExists(image) {
+					dropped = append(dropped, image)
+				}
+			}
+		}
+
+		for _, image := range old.Data.Images {
+			if st.Exists(image) {
+				continue
+			}
+
+			data, err := history.ReadAt(rev, image)
+			if err != nil {
+				return fmt.Errorf("Image '%s' does not exist at revision '%s'", image, rev)
+			}
+
+			if err := st.Write(image, data); err != nil {
+				return err
+			}
+		}
+
+		if err := st.Write(name, content); err != nil {
+			return err
+		}
+
+		store.RecipeAdd(name)
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		if _, err := removeUnreferenced(store, dropped); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "restore", messageData{Name: name, Rev: shortHash(rev)})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: Recipe is already at this version. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+package cmdline
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// imageIndex finds the image `ref` of `recipe`, given either by its
+// position as shown by `nom image list` (starting at 1) or by its path.
+func imageIndex(recipe *index.Recipe, ref string) (int, error) {
+	if position, err := strconv.Atoi(ref); err == nil {
+		if position < 1 || position > len(recipe.Data.Images) {
+			return 0, fmt.Errorf("Recipe '%s' has no image %d", recipe.Name, position)
+		}
+
+		return position - 1, nil
+	}
+
+	for idx, image := range recipe.Data.Images {
+		if image == ref {
+			return idx, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Recipe '%s' has no image '%s'", recipe.Name, ref)
+}
+
+// withRecipeImages loads the recipe `name`, lets `fn` change its images and
+// commits the result. `fn` returns the summary for the commit message.
+func withRecipeImages(store *index.Index, name string, fn func(recipe *index.Recipe) (string, error)) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	st := store.Store()
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(st); err != nil {
+		return err
+	}
+
+	old := append([]string{}, recipe.Data.Images...)
+
+	return st.WithTransaction(func() error {
+		summary, err := fn(&recipe)
+		if err != nil {
+			return err
+		}
+
+		if err := recipe.Save(st); err != nil {
+			return err
+		}
+
+		dropped := []string{}
+		for _, image := range old {
+			if !containsString(recipe.Data.Images, image) {
+				dropped = append(dropped, image)
+			}
+		}
+
+		if _, err := removeUnreferenced(store, dropped); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "image", messageData{Name: name, Summary: summary})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func handleImageList(store *index.Index, name string) error {
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(store.Store()); err != nil {
+		return err
+	}
+
+	for idx, image := range recipe.Data.Images {
+		mark := " "
+		if image == recipe.CoverImage() {
+			mark = "*"
+		}
+
+		fmt.Printf("%s %d %s\n", mark, idx+1, image)
+		if caption := recipe.Caption(image); caption != "" {
+			fmt.Printf("      %s\n", caption)
+		}
+	}
+
+	return nil
+}
+
+func handleImageRemove(store *index.Index, name string, refs []string) error {
+	return withRecipeImages(store, name, func(recipe *index.Recipe) (string, error) {
+		remove := make(map[string]bool)
+		for _, ref := range refs {
+			idx, err := imageIndex(recipe, ref)
+			if err != nil {
+				return "", err
+			}
+
+			remove[recipe.Data.Images[idx]] = true
+		}
+
+		images := []string{}
+		for _, image := range recipe.Data.Images {
+			if !remove[image] {
+				images = append(images, image)
+				continue
+			}
+
+			recipe.SetCaption(image, "")
+			if recipe.Data.Cover == image {
+				recipe.Data.Cover = ""
+			}
+		}
+
+		recipe.Data.Images = images
+		return fmt.Sprintf("-%d images", len(remove)), nil
+	})
+}
+
+func handleImageMove(store *index.Index, name, ref, to string) error {
+	return withRecipeImages(store, name, func(recipe *index.Recipe) (string, error) {
+		from, err := imageIndex(recipe, ref)
+		if err != nil {
+			return "", err
+		}
+
+		position, err := strconv.Atoi(to)
+		if err != nil || position < 1 || position > len(recipe.Data.Images) {
+			return "", fmt.Errorf("The new position must be between 1 and %d", len(recipe.Data.Images))
+		}
+
+		images := recipe.Data.Images
+		image := images[from]
+		images = append(images[:from:from], images[from+1:]...)
+		images = append(images[:position-1], append([]string{image}, images[position-1:]...)...)
+		recipe.Data.Images = images
+
+		return fmt.Sprintf("image %d moved to %d", from+1, position), nil
+	})
+}
+
+func handleImageCover(store *index.Index, name, ref string) error {
+	return withRecipeImages(store, name, func(recipe *index.Recipe) (string, error) {
+		idx, err := imageIndex(recipe, ref)
+		if err != nil {
+			return "", err
+		}
+
+		recipe.Data.Cover = recipe.Data.Images[idx]
+		return fmt.Sprintf("image %d is the cover", idx+1), nil
+	})
+}
+
+func handleImageCaption(store *index.Index, name, ref, caption string) error {
+	return withRecipeImages(store, name, func(recipe *index.Recipe) (string, error) {
+		idx, err := imageIndex(recipe, ref)
+		if err != nil {
+			return "", err
+		}
+
+		recipe.SetCaption(recipe.Data.Images[idx], caption)
+		if caption == "" {
+			return fmt.Sprintf("caption of image %d removed", idx+1), nil
+		}
+
+		return fmt.Sprintf("caption of image %d set", idx+1), nil
+	})
+}
@@ -0,0 +1,121 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/importer"
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// addImported writes an imported recipe and its images, normalized according
+// to `config`, as `name`. It does not commit, so several imports can go into one commit.
+func addImported(store *index.Index, name string, result *importer.Result, config *index.ImageConfig, force bool) error {
+	st := store.Store()
+	if !force {
+		if store.RecipeExists(name) {
+			return fmt.Errorf("Recipe '%s' already exists", name)
+		}
+
+		if err := guardExists(st, name); err != nil {
+			return err
+		}
+	}
+
+	recipe := result.Recipe
+	recipe.Name = name
+	recipe.Data.Images = []string{}
+
+	for _, image := range result.Images {
+		data, imageName, err := normalizeImage(config, image.Data, image.Name)
+		if err != nil {
+			return err
+		}
+
+		dest, err := storeImage(st, data, imageName)
+		if err != nil {
+			return err
+		}
+
+		if !containsString(recipe.Data.Images, dest) {
+			recipe.Data.Images = append(recipe.Data.Images, dest)
+		}
+	}
+
+	if err := recipe.Save(st); err != nil {
+		return err
+	}
+
+	store.RecipeAdd(name)
+	return store.Save()
+}
+
+func handleImport(store *index.Index, imp *importer.Importer, format, source, name string, force bool) error {
+	results, failures, err := imp.Import(format, source)
+	if err != nil {
+		return err
+	}
+
+	for _, failure := range failures {
+		fmt.Printf("Warning: Could not import %v\n", failure)
+	}
+
+	if name != "" && len(results) != 1 {
+		return fmt.Errorf("A name can only be given when importing a single recipe, found %d", len(results))
+	}
+
+	config, err := imageConfig(store, false)
+	if err != nil {
+		return err
+	}
+
+	st := store.Store()
+	return st.WithTransaction(func() error {
+		imported := []string{}
+		for _, result := range results {
+			target := name
+			if target == "" {
+				target = result.Name
+			}
+
+			if target == "" {
+				fmt.Printf("Warning: Skipping a recipe without name from '%s'.\n", result.Source)
+				continue
+			}
+
+			if !force && (store.RecipeExists(target) || st.Exists(target)) {
+				fmt.Printf("Warning: Skipping '%s' from '%s'; it already exists.\n", target, result.Source)
+				continue
+			}
+
+			if err := addImported(store, target, result, config, force); err != nil {
+				return err
+			}
+
+			imported = append(imported, target)
+			fmt.Printf("Info: Imported '%s' as '%s'.\n", result.Recipe.Data.Name, target)
+		}
+
+		if len(imported) == 0 {
+			return fmt.Errorf("Nothing was imported from '%s'", source)
+		}
+
+		data := messageData{Name: imported[0], Source: source}
+		if len(imported) > 1 {
+			data.Name = fmt.Sprintf("%d recipes", len(imported))
+		}
+
+		message, err := commitMessage(store, "import", data)
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No new things here. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
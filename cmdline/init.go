@@ -5,23 +5,26 @@ import (
 	"os"
 
 	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
 	"github.com/serztle/nom/util"
 )
 
-func handleInit(repoDir string) error {
+func handleInit(repoDir, author string) error {
 	if repoDir == "" {
 		repoDir = "."
 	}
 	if stat, err := os.Stat(repoDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(repoDir, 0700); err != nil {
+		if err := os.MkdirAll(repoDir, util.DirPerm); err != nil {
 			return err
 		}
 	} else if !stat.IsDir() {
 		return fmt.Errorf("%s already exists and is not a directory", repoDir)
 	}
 
-	store := index.NewIndex(repoDir)
-	git := util.NewGit(repoDir)
+	st := storage.NewFS(repoDir)
+	st.SetAuthor(author)
+	store := index.NewIndex(st)
+	git := st.Git()
 
 	if git.Exists() && store.Exists() {
 		return fmt.Errorf("There is already a nom archiv in '%s'. Nothing to do", repoDir)
@@ -31,25 +34,23 @@ func handleInit(repoDir string) error {
 		return fmt.Errorf("There is already a store file in '%s'", repoDir)
 	}
 
-	git.WithTransaction(func() error {
-		if err := git.Init(); err != nil {
-			return err
-		}
+	if err := git.Init(); err != nil {
+		return err
+	}
 
-		if err := store.Save(); err != nil {
+	return st.WithTransaction(func() error {
+		if err := registerMergeDriver(git); err != nil {
 			return err
 		}
 
-		if err := git.Add(store.Filename()); err != nil {
+		if err := st.Write(gitattributesPath, []byte(gitattributes)); err != nil {
 			return err
 		}
 
-		if err := git.Commit("nom initialized! 🍅"); err != nil {
+		if err := store.Save(); err != nil {
 			return err
 		}
 
-		return nil
+		return st.Commit("nom initialized! 🍅")
 	})
-
-	return nil
 }
@@ -1,38 +1,334 @@
 package cmdline
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/serztle/nom/index"
+	"gopkg.in/yaml.v2"
 )
 
-func handleList(store *index.Index, showImages bool) error {
+const (
+	// defaultListColumns are shown by list --format unless --columns is given.
+	defaultListColumns = "name,title,category,persons,duration,images"
+
+	modifiedFormat = "2006-01-02 15:04"
+)
+
+// listRow is one recipe as shown by list.
+type listRow struct {
+	recipe   index.Recipe
+	modified string
+	cooked   string
+}
+
+// listColumn is something list can show about a recipe. Rows are sorted by
+// comparing the values, unless the column knows better with `less`.
+type listColumn struct {
+	name  string
+	value func(row *listRow) interface{}
+	less  func(a, b *listRow) bool
+}
+
+var listColumns = []listColumn{{
+	name:  "name",
+	value: func(row *listRow) interface{} { return row.recipe.Name },
+}, {
+	name:  "title",
+	value: func(row *listRow) interface{} { return row.recipe.Data.Name },
+}, {
+	name:  "category",
+	value: func(row *listRow) interface{} { return row.recipe.Data.Category },
+}, {
+	name:  "persons",
+	value: func(row *listRow) interface{} { return int(row.recipe.Data.Persons) },
+}, {
+	name:  "duration",
+	value: func(row *listRow) interface{} { return row.recipe.Data.Duration.Total },
+	less: func(a, b *listRow) bool {
+		durationA, errA := index.ParseDuration(a.recipe.Data.Duration.Total)
+		durationB, errB := index.ParseDuration(b.recipe.Data.Duration.Total)
+		if errA != nil || errB != nil {
+			// Recipes without a valid duration go last.
+			return errA == nil && errB != nil
+		}
+
+		return durationA < durationB
+	},
+}, {
+	name:  "images",
+	value: func(row *listRow) interface{} { return len(row.recipe.Data.Images) },
+}, {
+	name:  "modified",
+	value: func(row *listRow) interface{} { return row.modified },
+}, {
+	name:  "cooked",
+	value: func(row *listRow) interface{} { return row.cooked },
+}}
+
+func listColumnNames() []string {
+	names := []string{}
+	for _, column := range listColumns {
+		names = append(names, column.name)
+	}
+
+	return names
+}
+
+func findListColumn(name string) (*listColumn, error) {
+	for idx := range listColumns {
+		if listColumns[idx].name == name {
+			return &listColumns[idx], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown column '%s', use one of: %s", name, strings.Join(listColumnNames(), ", "))
+}
+
+func parseListColumns(spec string) ([]*listColumn, error) {
+	columns := []*listColumn{}
+	for _, name := range strings.Split(spec, ",") {
+		column, err := findListColumn(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func lessListValue(a, b interface{}) bool {
+	if numberA, ok := a.(int); ok {
+		return numberA < b.(int)
+	}
+
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// listRecord is a row in json or yaml, keeping the order of the columns.
+type listRecord yaml.MapSlice
+
+func (r listRecord) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString("{")
+	for idx, item := range r {
+		if idx > 0 {
+			buf.WriteString(",")
+		}
+
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+func writeList(rows []*listRow, columns []*listColumn, format string) error {
+	switch format {
+	case "table":
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		header := []string{}
+		for _, column := range columns {
+			header = append(header, strings.ToUpper(column.name))
+		}
+
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, row := range rows {
+			cells := []string{}
+			for _, column := range columns {
+				cells = append(cells, fmt.Sprint(column.value(row)))
+			}
+
+			fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		}
+
+		return writer.Flush()
+	case "csv":
+		writer := csv.NewWriter(os.Stdout)
+		header := []string{}
+		for _, column := range columns {
+			header = append(header, column.name)
+		}
+
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			cells := []string{}
+			for _, column := range columns {
+				cells = append(cells, fmt.Sprint(column.value(row)))
+			}
+
+			if err := writer.Write(cells); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	case "json", "yaml":
+		records := []listRecord{}
+		for _, row := range rows {
+			record := listRecord{}
+			for _, column := range columns {
+				record = append(record, yaml.MapItem{Key: column.name, Value: column.value(row)})
+			}
+
+			records = append(records, record)
+		}
+
+		var content []byte
+		var err error
+		if format == "json" {
+			content, err = json.MarshalIndent(records, "", "    ")
+			content = append(content, '\n')
+		} else {
+			content, err = yaml.Marshal(records)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(content)
+		return err
+	default:
+		return fmt.Errorf("Unknown list format '%s', use one of: table, json, yaml, csv", format)
+	}
+}
+
+// handleList lists all recipes in use, or only the archived ones. Without
+// `format` each recipe is shown as "name (Display Name)", otherwise as a
+// row with `columns` (comma separated), ordered by the column `sortBy`.
+func handleList(store *index.Index, showImages, archived bool, format, columnSpec, sortBy string, reverse bool) error {
+	if format == "" && (columnSpec != "" || sortBy != "") {
+		format = "table"
+	}
+
+	if columnSpec == "" {
+		columnSpec = defaultListColumns
+	}
+
+	columns, err := parseListColumns(columnSpec)
+	if err != nil {
+		return err
+	}
+
+	sortColumn, err := findListColumn("name")
+	if sortBy != "" {
+		sortColumn, err = findListColumn(sortBy)
+	}
+
+	if err != nil {
+		return err
+	}
+
 	recipeNames := []string{}
 	for recipeName := range store.Recipes {
-		recipeNames = append(recipeNames, recipeName)
+		if store.RecipeArchived(recipeName) == archived {
+			recipeNames = append(recipeNames, recipeName)
+		}
 	}
 
 	sort.Strings(recipeNames)
 
-	recipes := []index.Recipe{}
+	wants := func(name string) bool {
+		if sortColumn.name == name {
+			return true
+		}
+
+		for _, column := range columns {
+			if column.name == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	cooked := index.NewCooked(store.Store())
+	if err := cooked.Parse(); err != nil {
+		return err
+	}
+
+	modified := map[string]time.Time{}
+	if format != "" && wants("modified") {
+		history, err := storeHistory(store)
+		if err != nil {
+			return err
+		}
+
+		if modified, err = history.Modified(); err != nil {
+			return err
+		}
+	}
+
+	rows := []*listRow{}
 	for _, recipeName := range recipeNames {
-		recipe := index.NewRecipe(store.RepoDir(), recipeName)
-		if err := recipe.Load(); err != nil {
+		row := &listRow{recipe: index.NewRecipe(recipeName)}
+		if err := row.recipe.Load(store.Store()); err != nil {
 			return err
 		}
-		recipes = append(recipes, recipe)
+
+		if date, ok := modified[recipeName]; ok {
+			row.modified = date.Format(modifiedFormat)
+		}
+
+		if date, ok := cooked.Last(recipeName); ok {
+			row.cooked = date.Format(index.CookedFormat)
+		}
+
+		rows = append(rows, row)
 	}
 
-	for _, recipe := range recipes {
-		fmt.Printf("%s (%s)\n", recipe.Name, recipe.Data.Name)
+	if format == "" {
+		for _, row := range rows {
+			fmt.Printf("%s (%s)\n", row.recipe.Name, row.recipe.Data.Name)
 
-		if showImages {
-			for _, image := range recipe.Data.Images {
-				fmt.Printf("    %s\n", image)
+			if showImages {
+				for _, image := range row.recipe.Data.Images {
+					fmt.Printf("    %s\n", image)
+				}
 			}
 		}
+
+		return nil
 	}
 
-	return nil
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if reverse {
+			a, b = b, a
+		}
+
+		if sortColumn.less != nil {
+			return sortColumn.less(a, b)
+		}
+
+		return lessListValue(sortColumn.value(a), sortColumn.value(b))
+	})
+
+	return writeList(rows, columns, format)
 }
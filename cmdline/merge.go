@@ -0,0 +1,132 @@
+package cmdline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+	"github.com/serztle/nom/util"
+)
+
+const (
+	gitattributesPath = ".gitattributes"
+	gitattributes     = `# Let nom merge its index, collections and recipes (see nom merge-driver).
+# Recipes have no file extension; other files are merged by git as usual.
+* merge=nom
+*.* !merge
+.nom merge=nom
+.collections merge=nom
+.images/** binary
+`
+)
+
+// registerMergeDriver tells git how to call `nom merge-driver`, which
+// has to be in $PATH. This lives in .git/config and is therefore not
+// shared by cloning.
+func registerMergeDriver(git *util.Git) error {
+	if err := git.Config("merge.nom.name", "nom recipe and index merge"); err != nil {
+		return err
+	}
+
+	return git.Config("merge.nom.driver", "nom merge-driver %O %A %B %P")
+}
+
+func mergeIndexes(contents [3][]byte) (string, error) {
+	scratch := storage.NewMemory()
+	versions := [3]*index.Index{}
+	for idx, content := range contents {
+		versions[idx] = index.NewIndex(scratch)
+		if err := versions[idx].Unmarshal(content); err != nil {
+			return "", err
+		}
+	}
+
+	merged := index.NewIndex(scratch)
+	merged.MergeIndex(versions[0].Recipes, versions[1].Recipes, versions[2].Recipes)
+	return merged.String()
+}
+
+func mergeCollections(contents [3][]byte) (string, error) {
+	scratch := storage.NewMemory()
+	versions := [3]*index.Collections{}
+	for idx, content := range contents {
+		versions[idx] = index.NewCollections(scratch)
+		if err := versions[idx].Unmarshal(content); err != nil {
+			return "", err
+		}
+	}
+
+	merged := index.NewCollections(scratch)
+	merged.MergeCollections(versions[0].Sets, versions[1].Sets, versions[2].Sets)
+	return merged.String()
+}
+
+// mergeRecipes returns false if the contents do not look like recipes at all.
+func mergeRecipes(name string, contents [3][]byte) (string, bool, error) {
+	empty := index.NewRecipe(name)
+	versions := [3]*index.Recipe{}
+	looksLikeRecipe := false
+	for idx, content := range contents {
+		recipe := index.NewRecipe(name)
+		if err := recipe.Unmarshal(content, name); err != nil {
+			return "", false, nil
+		}
+
+		if len(index.Diff(&empty, &recipe)) > 0 {
+			looksLikeRecipe = true
+		}
+
+		versions[idx] = &recipe
+	}
+
+	if !looksLikeRecipe {
+		return "", false, nil
+	}
+
+	merged, conflicts := index.Merge(versions[0], versions[1], versions[2])
+	for _, conflict := range conflicts {
+		fmt.Printf("Warning: '%s' %v\n", name, conflict)
+	}
+
+	content, err := merged.String()
+	return content, true, err
+}
+
+// handleMergeDriver is called by git as `nom merge-driver %O %A %B %P`.
+// The merged result is written to `ours`.
+func handleMergeDriver(base, ours, theirs, name string) error {
+	contents := [3][]byte{}
+	for idx, path := range []string{base, ours, theirs} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		contents[idx] = content
+	}
+
+	var merged string
+	var err error
+
+	switch name {
+	case index.IndexPath:
+		merged, err = mergeIndexes(contents)
+	case index.CollectionsPath:
+		merged, err = mergeCollections(contents)
+	default:
+		var isRecipe bool
+		merged, isRecipe, err = mergeRecipes(name, contents)
+		if err == nil && !isRecipe {
+			// Not ours to merge; fall back to what git would do.
+			return exec.Command("git", "merge-file", ours, base, theirs).Run()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return util.WriteFile(ours, []byte(merged))
+}
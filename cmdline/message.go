@@ -0,0 +1,94 @@
+package cmdline
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/serztle/nom/index"
+)
+
+// defaultMessages are the commit message templates used unless the
+// `messages` section of the repository config says otherwise.
+var defaultMessages = map[string]string{
+	"add":               "{{.Name}}: added",
+	"import":            "{{.Name}}: imported from {{.Source}}",
+	"image":             "{{.Name}}: {{.Summary}}",
+	"edit":              "{{.Name}}: {{.Summary}}",
+	"rm":                "{{.Name}}: removed",
+	"archive":           "{{.Name}}: archived",
+	"unarchive":         "{{.Name}}: unarchived",
+	"cook":              "{{.Name}}: cooked",
+	"mv":                "{{.Name}}: renamed to {{.NewName}}",
+	"restore":           "{{.Name}}: restored from {{.Rev}}",
+	"merge":             "{{.Name}}: merged with duplicate {{.NewName}}",
+	"collection-create": "collection {{.Name}}: created",
+	"collection-add":    "collection {{.Name}}: {{.Summary}}",
+	"collection-rm":     "collection {{.Name}}: {{.Summary}}",
+	"collection-delete": "collection {{.Name}}: removed",
+	"template-edit":     "template {{.Name}}: edited",
+	"template-rm":       "template {{.Name}}: removed",
+	"undo":              "undo: {{.Summary}}",
+	"gc":                "gc: {{.Summary}}",
+	"sync":              "sync: merged {{.Source}}",
+}
+
+// trailerKey marks commits made by nom; its value is the operation.
+const trailerKey = "Nom-Operation"
+
+// messageData is what commit message templates can refer to.
+type messageData struct {
+	Name    string
+	NewName string
+	Rev     string
+	Source  string
+	Summary string
+}
+
+// recipeSummary describes the changes between two versions of a recipe.
+func recipeSummary(old, new *index.Recipe) string {
+	summary := index.Summary(index.Diff(old, new))
+	if summary == "" {
+		return "changed"
+	}
+
+	return summary
+}
+
+// commitMessage renders the message template for `operation` and marks it
+// as made by nom.
+func commitMessage(store *index.Index, operation string, data messageData) (string, error) {
+	config := index.NewConfig(store.Store())
+	if err := config.Parse(); err != nil {
+		return "", err
+	}
+
+	tmplTxt, ok := config.Messages[operation]
+	if !ok {
+		tmplTxt = defaultMessages[operation]
+	}
+
+	tmpl, err := template.New(operation).Parse(tmplTxt)
+	if err != nil {
+		return "", fmt.Errorf("Bad commit message template for '%s' (%v)", operation, err)
+	}
+
+	message := bytes.Buffer{}
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("Bad commit message template for '%s' (%v)", operation, err)
+	}
+
+	return fmt.Sprintf("%s\n\n%s: %s", message.String(), trailerKey, operation), nil
+}
+
+// trailerValue returns the value of the trailer `key` in `message`, if any.
+func trailerValue(message, key string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, key+":") {
+			return strings.TrimSpace(strings.TrimPrefix(line, key+":"))
+		}
+	}
+
+	return ""
+}
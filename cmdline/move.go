@@ -2,71 +2,126 @@ package cmdline
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/serztle/nom/index"
-	"github.com/serztle/nom/util"
+	"github.com/serztle/nom/storage"
 )
 
-func handleMove(store *index.Index, name string, newName string, force bool) error {
+func handleMove(store *index.Index, name string, newName string, plans []string, force bool) error {
+	st := store.Store()
+	if !store.RecipeExists(name) {
+		return fmt.Errorf("No Recipe found with the name '%s'", name)
+	}
+
+	if name == newName {
+		return fmt.Errorf("Recipe '%s' already has this name", name)
+	}
+
 	if !force {
-		if err := util.GuardExists(filepath.Join(store.RepoDir(), newName)); err != nil {
+		if err := guardExists(st, newName); err != nil {
 			return err
 		}
 	}
 
-	namePath := filepath.Join(store.RepoDir(), name)
-	newNamePath := filepath.Join(store.RepoDir(), newName)
-	if err := os.Rename(namePath, newNamePath); err != nil {
+	content, err := st.Read(name)
+	if err != nil {
 		return err
 	}
 
-	imagePath := filepath.Join(store.RepoDir(), ".images", name)
-	newImagePath := filepath.Join(store.RepoDir(), ".images", newName)
-	if err := os.Rename(imagePath, newImagePath); err != nil {
+	recipe := index.NewRecipe(name)
+	if err := recipe.Unmarshal(content, name); err != nil {
 		return err
 	}
 
-	store.RecipeRemove(name)
-	store.RecipeAdd(newName)
-	if err := store.Save(); err != nil {
+	collections := index.NewCollections(st)
+	if err := collections.Parse(); err != nil {
 		return err
 	}
 
-	git := util.NewGit(store.RepoDir())
-	git.WithTransaction(func() error {
-		if err := git.Add(store.Filename()); err != nil {
+	cooked := index.NewCooked(st)
+	if err := cooked.Parse(); err != nil {
+		return err
+	}
+
+	err = st.WithTransaction(func() error {
+		if err := st.Delete(name); err != nil {
 			return err
 		}
 
-		if err := git.Add(name); err != nil {
-			return err
+		moved := recipe.Rename(newName)
+		for oldPath, newPath := range moved {
+			if !st.Exists(oldPath) {
+				continue
+			}
+
+			if err := st.Rename(oldPath, newPath); err != nil {
+				return err
+			}
 		}
 
-		if err := git.Add(filepath.Join(".images", name)); err != nil {
-			return err
+		// The file is only written anew if its images moved, so that its
+		// formatting and comments are kept otherwise.
+		var err error
+		if len(moved) > 0 {
+			err = recipe.Save(st)
+		} else {
+			err = st.Write(newName, content)
 		}
 
-		if err := git.Add(newName); err != nil {
+		if err != nil {
 			return err
 		}
 
-		if err := git.Add(filepath.Join(".images", newName)); err != nil {
-			return err
+		if st.Exists(collections.Filename()) {
+			collections.RecipeRename(name, newName)
+			if err := collections.Save(); err != nil {
+				return err
+			}
 		}
 
-		if git.HasChanges(true) {
-			// TODO: Better commit message?
-			if err := git.Commit("Recipe moved"); err != nil {
+		if st.Exists(cooked.Filename()) {
+			cooked.RecipeRename(name, newName)
+			if err := cooked.Save(); err != nil {
 				return err
 			}
-		} else {
+		}
+
+		archived := store.RecipeArchived(name)
+		store.RecipeRemove(name)
+		store.RecipeAdd(newName)
+		store.RecipeArchive(newName, archived)
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "mv", messageData{Name: name, NewName: newName})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
 			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
 		}
 
 		return nil
 	})
 
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		changed, err := renamePlanRecipe(plan, name, newName)
+		if err != nil {
+			return fmt.Errorf("Updating plan %s (%v)", plan, err)
+		}
+
+		if changed {
+			fmt.Printf("Info: Updated plan %s\n", plan)
+		}
+	}
+
 	return nil
 }
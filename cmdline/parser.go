@@ -2,11 +2,15 @@ package cmdline
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/serztle/nom/exporter"
+	"github.com/serztle/nom/importer"
 	"github.com/serztle/nom/index"
-	"github.com/serztle/nom/util"
+	"github.com/serztle/nom/storage"
 	"github.com/serztle/nom/view"
 	"github.com/urfave/cli"
 )
@@ -24,6 +28,7 @@ const (
 
 const (
 	DefaultPersons = 3
+	DefaultTimeout = 30 * time.Second
 )
 
 type checkFunc func(ctx *cli.Context) int
@@ -55,8 +60,9 @@ func needAtLeast(min int) checkFunc {
 }
 
 func CheckDir(dir string) error {
-	git := util.NewGit(dir)
-	store := index.NewIndex(dir)
+	st := storage.NewFS(dir)
+	git := st.Git()
+	store := index.NewIndex(st)
 
 	defaultError := fmt.Errorf("Seems not to be a nom archiv in '%s'", dir)
 
@@ -81,7 +87,10 @@ func withIndex(handler func(ctx *cli.Context, store *index.Index) error) func(*c
 			return err
 		}
 
-		store := index.NewIndex(repoDir)
+		st := storage.NewFS(repoDir)
+		st.SetAuthor(ctx.GlobalString("author"))
+
+		store := index.NewIndex(st)
 		if err := store.Parse(); err != nil {
 			return err
 		}
@@ -90,6 +99,25 @@ func withIndex(handler func(ctx *cli.Context, store *index.Index) error) func(*c
 	}
 }
 
+// guardExists returns an descriptive error for the user if `path` exists already.
+func guardExists(st storage.Store, path string) error {
+	if st.Exists(path) {
+		return fmt.Errorf("Guard: Destination file already exists (%s). Use --force to ignore this", path)
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, other := range values {
+		if other == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func formatGroup(category string) string {
 	return strings.ToUpper(category) + " COMMANDS"
 }
@@ -104,6 +132,7 @@ func Run() {
 	manageGroup := formatGroup("managing")
 	singleGroup := formatGroup("single recipes")
 	viewerGroup := formatGroup("viewing")
+	historyGroup := formatGroup("history")
 
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
@@ -120,6 +149,11 @@ func Run() {
 			Value:  ".",
 			EnvVar: "NOM_DIR",
 		},
+		cli.StringFlag{
+			Name:   "author",
+			Usage:  "Author of new commits, like 'Name <mail>'",
+			EnvVar: "NOM_AUTHOR",
+		},
 	}
 
 	flagPersons := cli.IntFlag{
@@ -128,6 +162,11 @@ func Run() {
 		Value: DefaultPersons,
 	}
 
+	flagCollection := cli.StringFlag{
+		Name:  "C,collection",
+		Usage: "Only use the recipes of this collection.",
+	}
+
 	app.Commands = []cli.Command{
 		{
 			Name:        "init",
@@ -140,93 +179,473 @@ func Run() {
 				if repoDir == "" {
 					repoDir = ctx.GlobalString("directory")
 				}
-				return handleInit(repoDir)
+				return handleInit(repoDir, ctx.GlobalString("author"))
+			},
+		}, {
+			Name:        "gc",
+			Category:    manageGroup,
+			Usage:       "Remove images no recipe uses anymore.",
+			ArgsUsage:   "[--dry-run]",
+			Description: "Remove all images below .images that are not referenced by any recipe and commit.",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "n,dry-run",
+					Usage: "Only show what would be removed.",
+				},
+			},
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleGC(store, ctx.Bool("dry-run"))
+			}),
+		}, {
+			Name:        "remote",
+			Category:    manageGroup,
+			Usage:       "Manage the git remotes to sync with.",
+			Description: "Manage the git remotes that nom sync exchanges recipes with.",
+			Subcommands: []cli.Command{
+				{
+					Name:        "add",
+					Usage:       "Add a new remote.",
+					ArgsUsage:   "<remote> <url>",
+					Description: "Add the git repository at <url> as <remote>.",
+					Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleRemoteAdd(store, ctx.Args().First(), ctx.Args().Get(1))
+					})),
+				},
 			},
+		}, {
+			Name:        "sync",
+			Category:    manageGroup,
+			Usage:       "Exchange recipes with a remote.",
+			ArgsUsage:   "[<remote>]",
+			Description: "Fetch from <remote> (or origin), merge resolving recipe conflicts field by field, and push.",
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleSync(store, ctx.Args().First())
+			}),
+		}, {
+			Name:        "merge-driver",
+			Hidden:      true,
+			Usage:       "Merge two versions of a nom file (called by git).",
+			ArgsUsage:   "<base> <ours> <theirs> [<path>]",
+			Description: "Three-way merge index, collections and recipes; registered in .gitattributes by nom init.",
+			Action: withArgCheck(needAtLeast(3), func(ctx *cli.Context) error {
+				args := ctx.Args()
+				return handleMergeDriver(args.Get(0), args.Get(1), args.Get(2), args.Get(3))
+			}),
 		}, {
 			Name:        "add",
 			Category:    singleGroup,
 			Usage:       "Add a new recipe.",
-			ArgsUsage:   "<name> <path> [(--image <path>)...]",
-			Description: "Add a new recipe with the handle `name` located at <path>, possibly with images.",
+			ArgsUsage:   "<name> [<path>] [(--image <path>)...] | --bulk <dir>",
+			Description: "Add a new recipe with the handle `name` located at <path>, possibly with images. Without <path> the recipe is written in $EDITOR or asked for with --wizard, pre-filled from the template of --template. Images are turned upright, stripped of metadata and shrunk as set in the images section of .nomconfig. With --bulk add all yaml files below <dir> in one commit, named like the files and with the subdirectories as category.",
 			Flags: []cli.Flag{
 				cli.StringSliceFlag{
 					Name:  "image",
 					Usage: "Path to an image file.",
 				},
+				cli.BoolFlag{
+					Name:  "w,wizard",
+					Usage: "Ask for the recipe step by step instead of opening an editor.",
+				},
+				cli.StringFlag{
+					Name:  "t,template",
+					Usage: "Start from the template of this category.",
+				},
+				cli.BoolFlag{
+					Name:  "keep-original",
+					Usage: "Store images as they are, without rotating, stripping metadata or resizing.",
+				},
+				cli.BoolFlag{
+					Name:  "bulk",
+					Usage: "Add all recipes below the directory given as argument.",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "With --bulk, add nothing if any recipe fails.",
+				},
 			},
 			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
 				name := ctx.Args().First()
 				path := ctx.Args().Get(1)
 
 				force, quiet := ctx.GlobalBool("force"), ctx.GlobalBool("quiet")
+				keepOriginal := ctx.Bool("keep-original")
+				if ctx.Bool("bulk") {
+					return handleAddBulk(store, name, force, ctx.Bool("strict"), keepOriginal)
+				}
+
 				images := ctx.StringSlice("image")
 
-				return handleAdd(store, name, path, force, quiet, images)
+				opts := newRecipeOptions{Category: ctx.String("template"), Wizard: ctx.Bool("wizard")}
+				return handleAdd(store, name, path, opts, force, quiet, keepOriginal, images)
+			})),
+		}, {
+			Name:        "image",
+			Category:    singleGroup,
+			Usage:       "Manage the images of a recipe.",
+			Description: "Images are given by their number as shown by `image list` or by their path. Add images with `add <name> --image <path>`.",
+			Subcommands: []cli.Command{
+				{
+					Name:        "list",
+					Usage:       "List the images of a recipe.",
+					ArgsUsage:   "<name>",
+					Description: "List the images of <name> with their numbers and captions; the cover is marked with '*'.",
+					Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleImageList(store, ctx.Args().First())
+					})),
+				}, {
+					Name:        "rm",
+					Usage:       "Remove images from a recipe.",
+					ArgsUsage:   "<name> <image>...",
+					Description: "Remove the images from <name>; their files go once no recipe uses them.",
+					Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleImageRemove(store, ctx.Args().First(), ctx.Args().Tail())
+					})),
+				}, {
+					Name:        "mv",
+					Usage:       "Move an image to another position.",
+					ArgsUsage:   "<name> <image> <position>",
+					Description: "Move <image> of <name> to <position>, counting from 1.",
+					Action: withArgCheck(needAtLeast(3), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						args := ctx.Args()
+						return handleImageMove(store, args.Get(0), args.Get(1), args.Get(2))
+					})),
+				}, {
+					Name:        "cover",
+					Usage:       "Choose the image shown in overviews.",
+					ArgsUsage:   "<name> <image>",
+					Description: "Show <image> for <name> in overviews instead of its first image.",
+					Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleImageCover(store, ctx.Args().Get(0), ctx.Args().Get(1))
+					})),
+				}, {
+					Name:        "caption",
+					Usage:       "Set or remove the caption of an image.",
+					ArgsUsage:   "<name> <image> [<caption>]",
+					Description: "Set the caption of <image>, or remove it if no <caption> is given.",
+					Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						args := ctx.Args()
+						return handleImageCaption(store, args.Get(0), args.Get(1), strings.Join(args[2:], " "))
+					})),
+				},
+			},
+		}, {
+			Name:        "import",
+			Category:    singleGroup,
+			Usage:       "Import recipes from web pages and other formats.",
+			ArgsUsage:   "<file|dir|url> [<name>]",
+			Description: "Import recipes with their images in one commit; for a directory every file of the format below it. Web pages need an embedded schema.org recipe (JSON-LD). A <name> can only be given for a single recipe.",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Usage: fmt.Sprintf("One of %s; detected from the file names if empty.", strings.Join(importer.FormatNames(), ", ")),
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "Give up fetching anything after this long.",
+					Value: DefaultTimeout,
+				},
+			},
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				imp := importer.New(&http.Client{Timeout: ctx.Duration("timeout")})
+				source, name := ctx.Args().First(), ctx.Args().Get(1)
+				return handleImport(store, imp, ctx.String("format"), source, name, ctx.GlobalBool("force"))
 			})),
 		}, {
 			Name:        "edit",
 			Category:    singleGroup,
 			Usage:       "Edit an existing recipe.",
 			ArgsUsage:   "<name>",
-			Description: "Open an existing recipe in $EDITOR and save it afterwards.",
+			Description: "Open an existing recipe in $EDITOR and save it afterwards. New images are copied into the store; relative image paths are relative to the repository.",
 			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
 				return handleEdit(store, ctx.Args().First())
 			})),
+		}, {
+			Name:        "get",
+			Category:    singleGroup,
+			Usage:       "Print a field of a recipe.",
+			ArgsUsage:   "<name> <field>",
+			Description: "Print <field> of <name>, one line per entry for lists. Fields are name, category, persons, duration.preparation, duration.cooking, duration.total, cover and the lists images, captions, ingredients, spices, complementaries, recipe and notes; list[n] is the nth entry.",
+			Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleGet(store, ctx.Args().Get(0), ctx.Args().Get(1))
+			})),
+		}, {
+			Name:        "set",
+			Category:    singleGroup,
+			Usage:       "Change a field of a recipe without an editor.",
+			ArgsUsage:   "<name> <field> [<value>...]",
+			Description: "Set <field> of <name> (see get) and commit. A list is replaced by all values; list[n] replaces the nth entry, list[+] appends values and list[-] removes the entries given by number or text.",
+			Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				args := ctx.Args()
+				return handleSet(store, args.Get(0), args.Get(1), args[2:])
+			})),
 		}, {
 			Name:        "rm",
 			Category:    singleGroup,
 			Usage:       "Remove an existing recipe.",
-			ArgsUsage:   "<name>",
-			Description: "Remove an existing recipe from the current database (may be restored with git)",
+			ArgsUsage:   "<name> [--archive]",
+			Description: "Remove an existing recipe from the current database (may be restored with git). With --archive it is only archived, see archive.",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "archive",
+					Usage: "Archive the recipe instead of deleting it.",
+				},
+			},
 			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				if ctx.Bool("archive") {
+					return handleArchive(store, ctx.Args().First(), true)
+				}
+
 				return handleRemove(store, ctx.Args().First())
 			})),
+		}, {
+			Name:        "archive",
+			Category:    singleGroup,
+			Usage:       "Hide a recipe without deleting it.",
+			ArgsUsage:   "<name>",
+			Description: "Keep <name> in the repository, but leave it out of plans, the web overview and list (unless --archived is given).",
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleArchive(store, ctx.Args().First(), true)
+			})),
+		}, {
+			Name:        "unarchive",
+			Category:    singleGroup,
+			Usage:       "Bring back an archived recipe.",
+			ArgsUsage:   "<name>",
+			Description: "Use the archived recipe <name> in plans, the web overview and list again.",
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleArchive(store, ctx.Args().First(), false)
+			})),
 		}, {
 			Name:        "mv",
 			Category:    singleGroup,
 			Usage:       "Rename an existing recipe.",
-			ArgsUsage:   "<old-name> <new-name>",
-			Description: "Give an existing recipe a new name.",
+			ArgsUsage:   "<old-name> <new-name> [(--plan <plan>)...]",
+			Description: "Give an existing recipe a new name. Its images, collections and the given plans are updated too.",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "P,plan",
+					Usage: "Also rename the recipe in a plan produced by the plan subcommand.",
+				},
+			},
 			Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
 				force := ctx.GlobalBool("force")
 				oldName := ctx.Args().First()
 				newName := ctx.Args().Get(1)
-				return handleMove(store, oldName, newName, force)
+				return handleMove(store, oldName, newName, ctx.StringSlice("plan"), force)
+			})),
+		}, {
+			Name:        "log",
+			Category:    historyGroup,
+			Usage:       "Show the history of the repository or a recipe.",
+			ArgsUsage:   "[<name>]",
+			Description: "List all commits touching the recipe <name> (or all commits) with date and message.",
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleLog(store, ctx.Args().First())
+			}),
+		}, {
+			Name:        "diff",
+			Category:    historyGroup,
+			Usage:       "Show what changed in a recipe.",
+			ArgsUsage:   "<name> [<rev>]",
+			Description: "Compare the recipe <name> at <rev> (or its previous version) field by field with the current one.",
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleDiff(store, ctx.Args().First(), ctx.Args().Get(1))
 			})),
+		}, {
+			Name:        "restore",
+			Category:    historyGroup,
+			Usage:       "Bring back an earlier version of a recipe.",
+			ArgsUsage:   "<name> [<rev>]",
+			Description: "Restore the recipe <name> with its images as it was at <rev> (or its previous version), even if it was removed.",
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleRestore(store, ctx.Args().First(), ctx.Args().Get(1))
+			})),
+		}, {
+			Name:        "undo",
+			Category:    historyGroup,
+			Usage:       "Revert the last nom operation.",
+			Description: "Revert the newest commit made by nom with a new commit. Use --force if other commits were made after it or if it was already pushed.",
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleUndo(store, ctx.GlobalBool("force"))
+			}),
+		}, {
+			Name:        "collection",
+			Category:    manageGroup,
+			Usage:       "Manage named collections of recipes.",
+			Description: "Group recipes into collections without moving any files.",
+			Subcommands: []cli.Command{
+				{
+					Name:        "create",
+					Usage:       "Create a new, empty collection.",
+					ArgsUsage:   "<collection>",
+					Description: "Create a new collection called <collection>.",
+					Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleCollectionCreate(store, ctx.Args().First())
+					})),
+				}, {
+					Name:        "add",
+					Usage:       "Add recipes to a collection.",
+					ArgsUsage:   "<collection> <name>...",
+					Description: "Add one or more recipes to an existing collection.",
+					Action: withArgCheck(needAtLeast(2), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleCollectionAdd(store, ctx.Args().First(), ctx.Args().Tail())
+					})),
+				}, {
+					Name:        "rm",
+					Usage:       "Remove recipes from a collection or the collection itself.",
+					ArgsUsage:   "<collection> [<name>...]",
+					Description: "Remove the given recipes from <collection>, or the whole collection if no recipes are given.",
+					Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleCollectionRemove(store, ctx.Args().First(), ctx.Args().Tail())
+					})),
+				}, {
+					Name:        "list",
+					Usage:       "List all collections or the recipes of one.",
+					ArgsUsage:   "[<collection>]",
+					Description: "List all collections, or all recipes in <collection>.",
+					Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleCollectionList(store, ctx.Args().First())
+					}),
+				},
+			},
+		}, {
+			Name:        "duplicates",
+			Category:    manageGroup,
+			Usage:       "Find recipes that are likely the same.",
+			ArgsUsage:   "[--threshold <score>] [--merge]",
+			Description: "List pairs of recipes with similar names and ingredients with their similarity, most similar first. With --merge ask for each pair whether to merge it.",
+			Flags: []cli.Flag{
+				cli.Float64Flag{
+					Name:  "threshold",
+					Usage: "Report pairs at least this similar, from 0 to 1.",
+					Value: index.DuplicateThreshold,
+				},
+				cli.BoolFlag{
+					Name:  "merge",
+					Usage: "Ask for each pair whether to merge one into the other.",
+				},
+			},
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleDuplicates(store, ctx.Float64("threshold"), ctx.Bool("merge"), os.Stdin)
+			}),
+		}, {
+			Name:        "template",
+			Category:    manageGroup,
+			Usage:       "Manage recipe templates per category.",
+			Description: "Templates pre-fill new recipes added without a file (see add --template and --wizard).",
+			Subcommands: []cli.Command{
+				{
+					Name:        "edit",
+					Usage:       "Create or change the template of a category.",
+					ArgsUsage:   "<category>",
+					Description: "Open the template for <category> in $EDITOR. New recipes of the category start from it.",
+					Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleTemplateEdit(store, ctx.Args().First())
+					})),
+				}, {
+					Name:        "rm",
+					Usage:       "Remove the template of a category.",
+					ArgsUsage:   "<category>",
+					Description: "Remove the template for <category>.",
+					Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleTemplateRemove(store, ctx.Args().First())
+					})),
+				}, {
+					Name:        "list",
+					Usage:       "List all categories with a template.",
+					Description: "List all categories with a template.",
+					Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+						return handleTemplateList(store)
+					}),
+				},
+			},
 		}, {
 			Name:        "list",
 			Category:    viewerGroup,
 			Usage:       "List all recipes.",
-			ArgsUsage:   "[--show-images]",
-			Description: "List all recipes and possibly all associated images.",
+			ArgsUsage:   "[--show-images] [--archived] [--format <format>] [--columns <columns>] [--sort <column>]",
+			Description: "List all recipes that are not archived (or only the archived ones) and possibly all associated images. With --format, show a row per recipe with the chosen columns: name, title, category, persons, duration, images, modified, cooked.",
 			Flags: []cli.Flag{
 				cli.BoolFlag{
 					Name:  "i,show-images",
 					Usage: "Show also the paths to all available images.",
 				},
+				cli.BoolFlag{
+					Name:  "a,archived",
+					Usage: "List the archived recipes instead.",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "Output as table, json, yaml or csv.",
+				},
+				cli.StringFlag{
+					Name:  "c,columns",
+					Usage: "Comma separated columns to show (default: " + defaultListColumns + ").",
+				},
+				cli.StringFlag{
+					Name:  "s,sort",
+					Usage: "Order the recipes by this column (default: name).",
+				},
+				cli.BoolFlag{
+					Name:  "r,reverse",
+					Usage: "Reverse the order.",
+				},
 			},
 			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
-				return handleList(store, ctx.Bool("show-images"))
+				return handleList(
+					store,
+					ctx.Bool("show-images"),
+					ctx.Bool("archived"),
+					ctx.String("format"),
+					ctx.String("columns"),
+					ctx.String("sort"),
+					ctx.Bool("reverse"),
+				)
 			}),
 		}, {
 			Name:        "grocery",
 			Category:    viewerGroup,
 			Usage:       "List all ingredients for your next supermarket visit.",
-			ArgsUsage:   "[<name>...] [(--plan <plan>)...]",
-			Description: "Create a grocery list for certain recipes or plans, multiplied to the person count.",
+			ArgsUsage:   "[<name>...] [(--plan <plan>)...] [(--collection <collection>)...]",
+			Description: "Create a grocery list for certain recipes, plans or collections, multiplied to the person count.",
 			Flags: []cli.Flag{
 				flagPersons,
 				cli.StringSliceFlag{
 					Name:  "P,plan",
 					Usage: "Generate groceries from a plan produced by the plan subcommand.",
 				},
+				cli.StringSliceFlag{
+					Name:  "C,collection",
+					Usage: "Generate groceries for all recipes of a collection.",
+				},
 			},
 			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
 				names := ctx.Args()
 				plans := ctx.StringSlice("plan")
+				collections := ctx.StringSlice("collection")
 				persons := ctx.Int("persons")
 
-				return handleGrocery(store, names, plans, persons)
+				return handleGrocery(store, names, plans, collections, persons)
+			}),
+		}, {
+			Name:        "export",
+			Category:    viewerGroup,
+			Usage:       "Export recipes to other formats.",
+			ArgsUsage:   "[<name>...]",
+			Description: "Write the recipes <name> (or all) with their images to a directory in one of: " + strings.Join(exporter.FormatNames(), ", ") + ".",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "The format to write.",
+					Value: "markdown",
+				},
+				cli.StringFlag{
+					Name:  "o,output",
+					Usage: "The directory to write to (required).",
+				},
+			},
+			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleExport(store, ctx.String("format"), ctx.Args(), ctx.String("output"))
 			}),
 		}, {
 			Name:        "serve",
@@ -238,17 +657,31 @@ func Run() {
 					Name:  "static-dir",
 					Usage: "Do not serve, render files static to this directory.",
 				},
+				flagCollection,
 			},
 			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				store, err := filterCollection(store, ctx.String("collection"))
+				if err != nil {
+					return err
+				}
+
 				return view.Serve(store, ctx.String("static-dir"))
 			}),
 		}, {
 			Name:        "plan",
 			Category:    viewerGroup,
 			Usage:       "Produce a recipe plan for a certain timespan",
-			ArgsUsage:   "[<from-date> [<to-date>]]",
-			Description: "Produce a recipe plan starting at <from-date> (or today) and ending at <to-date>.",
+			ArgsUsage:   "[<from-date> [<to-date>]] [--collection <collection>]",
+			Description: "Produce a recipe plan starting at <from-date> (or today) and ending at <to-date>, keeping the rules in the plan section of .nomconfig: " + strings.Join([]string{index.PlanRuleMaxPreparation, index.PlanRuleRequire, index.PlanRuleExclude, index.PlanRuleMaxPerWeek, index.PlanRuleNoRepeatCategory, index.PlanRuleMinGap}, ", ") + ". Rules that could not be kept are explained.",
+			Flags: []cli.Flag{
+				flagCollection,
+			},
 			Action: withIndex(func(ctx *cli.Context, store *index.Index) error {
+				store, err := filterCollection(store, ctx.String("collection"))
+				if err != nil {
+					return err
+				}
+
 				fromDate := ctx.Args().First()
 				toDate := ctx.Args().Get(1)
 				return handlePlan(store, fromDate, toDate)
@@ -267,6 +700,15 @@ func Run() {
 				persons := ctx.Int("persons")
 				return handleCook(store, name, persons)
 			})),
+		}, {
+			Name:        "cooked",
+			Category:    singleGroup,
+			Usage:       "Remember when a recipe was cooked.",
+			ArgsUsage:   "<name> [<date>]",
+			Description: "Note that <name> was cooked today or on <date> (like 2016-08-01). list shows it in the cooked column and plan keeps min-gap rules with it.",
+			Action: withArgCheck(needAtLeast(1), withIndex(func(ctx *cli.Context, store *index.Index) error {
+				return handleCooked(store, ctx.Args().First(), ctx.Args().Get(1))
+			})),
 		},
 	}
 
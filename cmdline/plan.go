@@ -2,12 +2,14 @@ package cmdline
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
-	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/util"
 	"gopkg.in/yaml.v2"
 )
 
@@ -39,7 +41,11 @@ func convertDates(fromDate, toDate string, days int) (*time.Time, int, error) {
 	return &from, days, nil
 }
 
+// handlePlan plans a recipe for each day using the rules in the `plan`
+// section of the config. The plan is written to stdout; rules that could
+// not be kept are explained on stderr, so the plan can still be saved.
 func handlePlan(store *index.Index, fromDate string, toDate string) error {
+	store = store.Active()
 	if len(store.Recipes) == 0 {
 		return fmt.Errorf("No recipes found")
 	}
@@ -49,29 +55,37 @@ func handlePlan(store *index.Index, fromDate string, toDate string) error {
 		return err
 	}
 
-	rand.Seed(time.Now().UnixNano())
+	config := index.NewConfig(store.Store())
+	if err := config.Parse(); err != nil {
+		return err
+	}
 
-	var indexes []int
-	var recipeNames []string
-	for recipeName := range store.Recipes {
-		recipeNames = append(recipeNames, recipeName)
+	cooked := index.NewCooked(store.Store())
+	if err := cooked.Parse(); err != nil {
+		return err
 	}
 
-	idx := len(store.Recipes)
-	dateToRecipe := make(map[string]string)
+	planner, err := index.NewPlanner(store, config, cooked, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	dates := []time.Time{}
 	for day := 0; day <= days; day++ {
-		if idx >= len(recipeNames) {
-			indexes = rand.Perm(len(recipeNames))
-			idx = 0
-		}
+		dates = append(dates, from.Add(time.Hour*24*time.Duration(day)))
+	}
 
-		stamp := from.Add(time.Hour * 24 * time.Duration(day))
-		dateToRecipe[stamp.Format(dateFormat)] = recipeNames[indexes[idx]]
-		idx++
+	plan, err := planner.Plan(dates)
+	if err != nil {
+		return err
 	}
 
-	content, err := yaml.Marshal(dateToRecipe)
+	dateToRecipe := make(map[string]string)
+	for idx, date := range plan.Dates {
+		dateToRecipe[date.Format(dateFormat)] = plan.Recipes[idx]
+	}
 
+	content, err := yaml.Marshal(dateToRecipe)
 	if err != nil {
 		return err
 	}
@@ -80,5 +94,78 @@ func handlePlan(store *index.Index, fromDate string, toDate string) error {
 		return err
 	}
 
+	explainPlan(planner, plan)
 	return nil
 }
+
+// explainPlan tells which rules the plan breaks on which days.
+func explainPlan(planner *index.Planner, plan *index.Plan) {
+	rules := []*index.PlanRule{}
+	days := make(map[*index.PlanRule][]string)
+	for _, violation := range plan.Violations {
+		if _, ok := days[violation.Rule]; !ok {
+			rules = append(rules, violation.Rule)
+		}
+
+		day := fmt.Sprintf("%s (%s)", violation.Date.Format(dateFormat), violation.Recipe)
+		days[violation.Rule] = append(days[violation.Rule], day)
+	}
+
+	for _, rule := range rules {
+		level := "Warning"
+		if rule.Soft {
+			level = "Info"
+		}
+
+		fmt.Fprintf(
+			os.Stderr,
+			"%s: Rule '%s' is not kept on %s: %s\n",
+			level,
+			rule,
+			strings.Join(days[rule], ", "),
+			planner.Explain(rule),
+		)
+	}
+}
+
+// renamePlanRecipe replaces `name` by `newName` in the plan file at `path`.
+// It returns false if the plan did not mention `name`.
+func renamePlanRecipe(path, name, newName string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	dateToRecipe := make(map[string]string)
+	if err := yaml.Unmarshal(content, &dateToRecipe); err != nil {
+		return false, fmt.Errorf("Seems like plan %s is not valid yaml (%v)!", path, err)
+	}
+
+	changed := false
+	for date, recipe := range dateToRecipe {
+		if recipe == name {
+			dateToRecipe[date] = newName
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	content, err = yaml.Marshal(dateToRecipe)
+	if err != nil {
+		return false, err
+	}
+
+	if err := util.WriteFile(path, content); err != nil {
+		return false, err
+	}
+
+	return true, os.Chmod(path, info.Mode().Perm())
+}
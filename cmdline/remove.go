@@ -2,51 +2,64 @@ package cmdline
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/serztle/nom/index"
-	"github.com/serztle/nom/util"
 )
 
 func handleRemove(store *index.Index, name string) error {
-	pathName := filepath.Join(store.RepoDir(), name)
-
 	if !store.RecipeExists(name) {
 		return fmt.Errorf("Info: No Recipe found with the name '%s'\n", name)
 	}
 
-	store.RecipeRemove(name)
-	if err := store.Save(); err != nil {
+	st := store.Store()
+	recipe := index.NewRecipe(name)
+	if err := recipe.Load(st); err != nil {
 		return err
 	}
 
-	recipe := index.Recipe{}
-	if err := recipe.Parse(pathName); err != nil {
-		return err
-	}
+	return st.WithTransaction(func() error {
+		store.RecipeRemove(name)
+		if err := store.Save(); err != nil {
+			return err
+		}
+
+		collections := index.NewCollections(st)
+		if err := collections.Parse(); err != nil {
+			return err
+		}
 
-	git := util.NewGit(store.RepoDir())
-	git.WithTransaction(func() error {
-		for _, image := range recipe.Data.Images {
-			if err := git.Rm(image); err != nil {
+		if st.Exists(collections.Filename()) {
+			collections.RecipeForget(name)
+			if err := collections.Save(); err != nil {
 				return err
 			}
 		}
 
-		if err := git.Rm(name); err != nil {
+		cooked := index.NewCooked(st)
+		if err := cooked.Parse(); err != nil {
 			return err
 		}
 
-		if err := git.Add(store.Filename()); err != nil {
+		if st.Exists(cooked.Filename()) {
+			cooked.RecipeForget(name)
+			if err := cooked.Save(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := removeUnreferenced(store, recipe.Data.Images); err != nil {
 			return err
 		}
 
-		if err := git.Commit("Recipe removed"); err != nil {
+		if err := st.Delete(name); err != nil {
 			return err
 		}
 
-		return nil
-	})
+		message, err := commitMessage(store, "rm", messageData{Name: name})
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return st.Commit(message)
+	})
 }
@@ -0,0 +1,289 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+	"github.com/serztle/nom/util"
+)
+
+const (
+	DefaultRemote = "origin"
+)
+
+func repoGit(store *index.Index) (*util.Git, error) {
+	fs, ok := store.Store().(*storage.FS)
+	if !ok {
+		return nil, fmt.Errorf("This store is not backed by a git repository")
+	}
+
+	return fs.Git(), nil
+}
+
+// conflictStages returns the base, our and their version of `path`.
+// Versions that do not exist (e.g. because a side removed the file) are nil.
+func conflictStages(git *util.Git, path string) [3][]byte {
+	stages := [3][]byte{}
+	for stage := 1; stage <= 3; stage++ {
+		if data, err := git.ShowStage(stage, path); err == nil {
+			stages[stage-1] = data
+		}
+	}
+
+	return stages
+}
+
+func resolveIndex(store *index.Index, git *util.Git, kept []string, conflicted bool) error {
+	merged := index.NewIndex(store.Store())
+	if conflicted {
+		versions := [3]*index.Index{}
+		for idx, data := range conflictStages(git, store.Filename()) {
+			versions[idx] = index.NewIndex(store.Store())
+			if err := versions[idx].Unmarshal(data); err != nil {
+				return err
+			}
+		}
+
+		merged.MergeIndex(versions[0].Recipes, versions[1].Recipes, versions[2].Recipes)
+	} else if err := merged.Parse(); err != nil {
+		return err
+	}
+
+	for _, name := range kept {
+		merged.RecipeAdd(name)
+	}
+
+	if err := merged.Save(); err != nil {
+		return err
+	}
+
+	return git.Add(merged.Filename())
+}
+
+func resolveCollections(store *index.Index, git *util.Git, path string) error {
+	versions := [3]*index.Collections{}
+	for idx, data := range conflictStages(git, path) {
+		versions[idx] = index.NewCollections(store.Store())
+		if err := versions[idx].Unmarshal(data); err != nil {
+			return err
+		}
+	}
+
+	merged := index.NewCollections(store.Store())
+	merged.MergeCollections(versions[0].Sets, versions[1].Sets, versions[2].Sets)
+	if err := merged.Save(); err != nil {
+		return err
+	}
+
+	return git.Add(merged.Filename())
+}
+
+// resolveFile keeps our version of `path` if there is one, else theirs.
+func resolveFile(store *index.Index, git *util.Git, path string) error {
+	stages := conflictStages(git, path)
+	data := stages[1]
+	if data == nil {
+		data = stages[2]
+	}
+
+	if data == nil {
+		return git.Remove(path)
+	}
+
+	fmt.Printf("Warning: '%s' was changed on both sides. Keeping the local version.\n", path)
+	if err := store.Store().Write(path, data); err != nil {
+		return err
+	}
+
+	return git.Add(path)
+}
+
+// resolveRecipe merges `name` field by field. It returns true if the recipe was
+// removed on one side but changed on the other and is therefore kept.
+func resolveRecipe(store *index.Index, git *util.Git, name string) (bool, error) {
+	versions := [3]*index.Recipe{}
+	for idx, data := range conflictStages(git, name) {
+		if data == nil {
+			continue
+		}
+
+		recipe := index.NewRecipe(name)
+		if err := recipe.Unmarshal(data, name); err != nil {
+			return false, err
+		}
+
+		versions[idx] = &recipe
+	}
+
+	base, ours, theirs := versions[0], versions[1], versions[2]
+	if ours == nil && theirs == nil {
+		return false, git.Remove(name)
+	}
+
+	if ours == nil || theirs == nil {
+		fmt.Printf("Warning: '%s' was removed on one side and changed on the other. Keeping it.\n", name)
+		if err := resolveFile(store, git, name); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	if base == nil {
+		empty := index.NewRecipe(name)
+		base = &empty
+	}
+
+	merged, conflicts := index.Merge(base, ours, theirs)
+	for _, conflict := range conflicts {
+		fmt.Printf("Warning: '%s' %v\n", name, conflict)
+	}
+
+	if err := merged.Save(store.Store()); err != nil {
+		return false, err
+	}
+
+	return false, git.Add(name)
+}
+
+// indexedRecipes returns the names of the recipes in our and their index.
+func indexedRecipes(store *index.Index, git *util.Git) (map[string]bool, error) {
+	names := make(map[string]bool)
+	for _, rev := range []string{"HEAD", "MERGE_HEAD"} {
+		data, err := git.Show(rev, store.Filename())
+		if err != nil {
+			continue
+		}
+
+		version := index.NewIndex(store.Store())
+		if err := version.Unmarshal(data); err != nil {
+			return nil, err
+		}
+
+		for name := range version.Recipes {
+			names[name] = true
+		}
+	}
+
+	return names, nil
+}
+
+// resolveConflicts resolves the conflicts nom understands. Paths that are
+// neither nom's own files nor recipes are left to the user and returned.
+func resolveConflicts(store *index.Index, git *util.Git, paths []string) ([]string, error) {
+	kept := []string{}
+	unresolved := []string{}
+	indexConflicted := false
+
+	recipes, err := indexedRecipes(store, git)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		switch {
+		case path == store.Filename():
+			indexConflicted = true
+		case path == index.CollectionsPath:
+			if err := resolveCollections(store, git, path); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(path, ".images/"):
+			if err := resolveFile(store, git, path); err != nil {
+				return nil, err
+			}
+		case recipes[path]:
+			keep, err := resolveRecipe(store, git, path)
+			if err != nil {
+				return nil, err
+			}
+
+			if keep {
+				kept = append(kept, path)
+			}
+		default:
+			unresolved = append(unresolved, path)
+		}
+	}
+
+	return unresolved, resolveIndex(store, git, kept, indexConflicted)
+}
+
+func handleRemoteAdd(store *index.Index, name, url string) error {
+	git, err := repoGit(store)
+	if err != nil {
+		return err
+	}
+
+	return git.RemoteAdd(name, url)
+}
+
+func handleSync(store *index.Index, remote string) error {
+	if remote == "" {
+		remote = DefaultRemote
+	}
+
+	git, err := repoGit(store)
+	if err != nil {
+		return err
+	}
+
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	if err := registerMergeDriver(git); err != nil {
+		return err
+	}
+
+	if err := git.Fetch(remote); err != nil {
+		return err
+	}
+
+	remoteRef := remote + "/" + branch
+	if git.RefExists(remoteRef) {
+		if err := git.Merge(remoteRef); err != nil {
+			conflicts, errConflicts := git.Conflicts()
+			if errConflicts != nil || len(conflicts) == 0 {
+				git.Exec("merge", "--abort")
+				return err
+			}
+
+			unresolved, err := resolveConflicts(store, git, conflicts)
+			if err != nil {
+				git.Exec("merge", "--abort")
+				return err
+			}
+
+			if len(unresolved) > 0 {
+				return fmt.Errorf(
+					"Could not merge %s. Resolve the conflicts with git, commit and sync again",
+					strings.Join(unresolved, ", "),
+				)
+			}
+		}
+
+		// Fast-forwards leave nothing to commit.
+		if git.RefExists("MERGE_HEAD") {
+			message, err := commitMessage(store, "sync", messageData{Source: remoteRef})
+			if err != nil {
+				return err
+			}
+
+			// The merge has to be recorded even if it changed nothing.
+			err = store.Store().Commit(message)
+			if err == storage.ErrNoChanges {
+				err = git.Commit(message)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return git.Push(remote, branch)
+}
@@ -0,0 +1,95 @@
+package cmdline
+
+import (
+	"fmt"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+	"github.com/serztle/nom/util"
+)
+
+func handleTemplateList(store *index.Index) error {
+	names, err := index.TemplateNames(store.Store())
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+func handleTemplateEdit(store *index.Index, category string) error {
+	if err := index.CheckTemplateCategory(category); err != nil {
+		return err
+	}
+
+	st := store.Store()
+	template, ok, err := index.LoadTemplate(st, category)
+	if err != nil {
+		return err
+	} else if !ok {
+		template = &index.Recipe{}
+		template.Data.Category = category
+	}
+
+	content, err := template.String()
+	if err != nil {
+		return err
+	}
+
+	edited, err := util.EditData([]byte(content))
+	if err != nil {
+		return err
+	}
+
+	// Only check that the template is a valid recipe.
+	if err := (&index.Recipe{}).Unmarshal(edited, index.TemplatePath(category)); err != nil {
+		return err
+	}
+
+	return st.WithTransaction(func() error {
+		if err := st.Write(index.TemplatePath(category), edited); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "template-edit", messageData{Name: category})
+		if err != nil {
+			return err
+		}
+
+		if err := st.Commit(message); err == storage.ErrNoChanges {
+			fmt.Println("Info: No changes. Nothing to do.")
+		} else if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func handleTemplateRemove(store *index.Index, category string) error {
+	if err := index.CheckTemplateCategory(category); err != nil {
+		return err
+	}
+
+	st := store.Store()
+	if !st.Exists(index.TemplatePath(category)) {
+		return fmt.Errorf("There is no template for '%s'", category)
+	}
+
+	return st.WithTransaction(func() error {
+		if err := st.Delete(index.TemplatePath(category)); err != nil {
+			return err
+		}
+
+		message, err := commitMessage(store, "template-rm", messageData{Name: category})
+		if err != nil {
+			return err
+		}
+
+		return st.Commit(message)
+	})
+}
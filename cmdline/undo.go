@@ -0,0 +1,69 @@
+package cmdline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+func handleUndo(store *index.Index, force bool) error {
+	git, err := repoGit(store)
+	if err != nil {
+		return err
+	}
+
+	entries, err := git.Log("")
+	if err != nil {
+		return err
+	}
+
+	// Walk back to the newest commit made by nom.
+	rev, message := "", ""
+	for idx, entry := range entries {
+		if message, err = git.Message(entry.Hash); err != nil {
+			return err
+		}
+
+		if trailerValue(message, trailerKey) == "" {
+			continue
+		}
+
+		if idx > 0 && !force {
+			return fmt.Errorf(
+				"The last nom commit '%s' was followed by %d other commit(s). Use --force to undo it anyway",
+				entry.Message, idx,
+			)
+		}
+
+		rev = entry.Hash
+		break
+	}
+
+	if rev == "" {
+		return fmt.Errorf("There is no commit made by nom to undo")
+	}
+
+	if git.IsPushed(rev) && !force {
+		return fmt.Errorf("The last nom commit was already pushed. Use --force to undo it anyway")
+	}
+
+	if err := git.Revert(rev); err != nil {
+		git.Exec("revert", "--abort")
+		return err
+	}
+
+	subject := strings.SplitN(strings.TrimSpace(message), "\n", 2)[0]
+	undoMessage, err := commitMessage(store, "undo", messageData{Summary: subject})
+	if err == nil {
+		err = git.Commit(undoMessage)
+	}
+
+	if err != nil {
+		git.Exec("revert", "--abort")
+		return err
+	}
+
+	fmt.Printf("Info: Undid '%s'.\n", subject)
+	return nil
+}
@@ -0,0 +1,153 @@
+package cmdline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// wizard asks for the parts of a recipe one by one on a terminal.
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	eof bool
+}
+
+func newWizard(in io.Reader, out io.Writer) *wizard {
+	return &wizard{in: bufio.NewReader(in), out: out}
+}
+
+// line reads one trimmed line; at the end of input it returns "".
+func (w *wizard) line() string {
+	if w.eof {
+		return ""
+	}
+
+	text, err := w.in.ReadString('\n')
+	if err != nil {
+		w.eof = true
+		fmt.Fprintln(w.out)
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// ask prompts for a value; an empty answer keeps `def`. `check` may
+// reject an answer, which is then asked for again.
+func (w *wizard) ask(question, def string, check func(string) error) string {
+	for {
+		if def != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", question)
+		}
+
+		answer := w.line()
+		if answer == "" {
+			answer = def
+		}
+
+		if check == nil || answer == "" {
+			return answer
+		}
+
+		if err := check(answer); err != nil {
+			fmt.Fprintf(w.out, "Error: %v\n", err)
+			if w.eof {
+				return def
+			}
+
+			continue
+		}
+
+		return answer
+	}
+}
+
+// list shows the `current` entries and reads more until an empty line.
+func (w *wizard) list(title string, current []string) []string {
+	fmt.Fprintf(w.out, "%s, one per line; finish with an empty line:\n", title)
+	for _, entry := range current {
+		fmt.Fprintf(w.out, "  - %s\n", entry)
+	}
+
+	entries := append([]string{}, current...)
+	for {
+		fmt.Fprint(w.out, "  - ")
+		entry := w.line()
+		if entry == "" {
+			return entries
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+func checkPersons(answer string) error {
+	if count, err := strconv.ParseUint(answer, 10, 32); err != nil || count < 1 {
+		return fmt.Errorf("Persons must be a positive number")
+	}
+
+	return nil
+}
+
+func checkDuration(answer string) error {
+	if _, err := index.ParseDuration(answer); err != nil {
+		return fmt.Errorf("Durations look like 45m, 2h or 1h30m")
+	}
+
+	return nil
+}
+
+// runWizard fills `recipe` by asking. Once the category is known, its
+// template (if any) pre-fills everything that follows.
+func runWizard(st storage.Store, recipe *index.Recipe, in io.Reader, out io.Writer) error {
+	w := newWizard(in, out)
+
+	name := recipe.Data.Name
+	if name == "" {
+		name = recipe.Name
+	}
+
+	name = w.ask("Name", name, nil)
+	category := w.ask("Category", recipe.Data.Category, nil)
+
+	// Categories that can't have a template just don't get one.
+	template, ok := (*index.Recipe)(nil), false
+	if index.CheckTemplateCategory(category) == nil {
+		var err error
+		if template, ok, err = index.LoadTemplate(st, category); err != nil {
+			return err
+		}
+	}
+
+	if ok {
+		fmt.Fprintf(out, "Info: Using the template for '%s'.\n", category)
+		recipe.Data = template.Data
+	}
+
+	recipe.Data.Name, recipe.Data.Category = name, category
+	if recipe.Data.Persons == 0 {
+		recipe.Data.Persons = DefaultPersons
+	}
+
+	persons := w.ask("Persons", strconv.FormatUint(uint64(recipe.Data.Persons), 10), checkPersons)
+	count, _ := strconv.ParseUint(persons, 10, 32)
+	recipe.Data.Persons = uint(count)
+
+	duration := &recipe.Data.Duration
+	duration.Preparation = w.ask("Preparation time", duration.Preparation, checkDuration)
+	duration.Cooking = w.ask("Cooking time", duration.Cooking, checkDuration)
+	duration.Total = w.ask("Total time", duration.Total, checkDuration)
+
+	recipe.Data.Ingredients = w.list("Ingredients", recipe.Data.Ingredients)
+	recipe.Data.Spices = w.list("Spices", recipe.Data.Spices)
+	recipe.Data.Complementaries = w.list("Complementaries", recipe.Data.Complementaries)
+	recipe.Data.Recipe = w.list("Steps", recipe.Data.Recipe)
+	return nil
+}
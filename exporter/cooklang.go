@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+// cooklang writes Cooklang (https://cooklang.org). Ingredients are marked
+// where the steps mention them; the others are listed in a first step.
+// Cooklang finds the first image by the recipe's file name.
+type cooklang struct{}
+
+func (cooklang) Ext() string {
+	return ".cook"
+}
+
+func (cooklang) Export(recipe *index.Recipe, images []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	metadata := [][2]string{
+		{"title", recipe.Data.Name},
+		{"tags", recipe.Data.Category},
+		{"prep time", recipe.Data.Duration.Preparation},
+		{"cook time", recipe.Data.Duration.Cooking},
+		{"time", recipe.Data.Duration.Total},
+	}
+	if recipe.Data.Persons > 0 {
+		metadata = append(metadata, [2]string{"servings", fmt.Sprintf("%d", recipe.Data.Persons)})
+	}
+
+	for _, entry := range metadata {
+		if entry[1] != "" {
+			fmt.Fprintf(buf, ">> %s: %s\n", entry[0], entry[1])
+		}
+	}
+
+	steps := []string{}
+	for _, step := range recipe.Data.Recipe {
+		steps = append(steps, strings.Join(strings.Fields(step), " "))
+	}
+
+	unmentioned := []string{}
+	all := append(append(append([]string{}, recipe.Data.Ingredients...), recipe.Data.Spices...), recipe.Data.Complementaries...)
+	for _, ingredient := range all {
+		quantity, unit, name := splitIngredient(ingredient)
+		name = strings.Map(func(char rune) rune {
+			if strings.ContainsRune("@#~{}", char) {
+				return -1
+			}
+			return char
+		}, name)
+
+		amount := quantity
+		if unit != "" {
+			amount += "%" + unit
+		}
+
+		token := "@" + name + "{" + amount + "}"
+		if !markIngredient(steps, name, token) {
+			unmentioned = append(unmentioned, token)
+		}
+	}
+
+	if len(unmentioned) > 0 {
+		steps = append([]string{strings.Join(unmentioned, ", ")}, steps...)
+	}
+
+	for _, step := range steps {
+		fmt.Fprintf(buf, "\n%s\n", step)
+	}
+
+	if len(recipe.Data.Notes) > 0 {
+		buf.WriteString("\n")
+		for _, note := range recipe.Data.Notes {
+			fmt.Fprintf(buf, "> %s\n", strings.Join(strings.Fields(note), " "))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// markIngredient replaces the first plain mention of `name` in `steps` by `token`.
+func markIngredient(steps []string, name, token string) bool {
+	if name == "" {
+		return false
+	}
+
+	for idx, step := range steps {
+		for offset := 0; offset < len(step); {
+			pos := strings.Index(step[offset:], name)
+			if pos < 0 {
+				break
+			}
+
+			pos += offset
+			if pos == 0 || !strings.ContainsRune("@{", rune(step[pos-1])) {
+				steps[idx] = step[:pos] + token + step[pos+len(name):]
+				return true
+			}
+
+			offset = pos + len(name)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/serztle/nom/index"
+	"github.com/serztle/nom/storage"
+)
+
+// Format writes recipes in one foreign format.
+type Format interface {
+	// Ext is the file extension of exported recipes, like ".json".
+	Ext() string
+	// Export returns `recipe` in this format. `images` are the file
+	// names of its images, which are written next to the recipe.
+	Export(recipe *index.Recipe, images []string) ([]byte, error)
+}
+
+// Formats are all known export formats by name.
+var Formats = map[string]Format{
+	"json":     jsonFormat{},
+	"jsonld":   jsonLD{},
+	"cooklang": cooklang{},
+	"markdown": markdown{},
+}
+
+// FormatNames returns the names of all known formats, sorted.
+func FormatNames() []string {
+	names := []string{}
+	for name := range Formats {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ImageNames returns the file names of the images of `recipe` next to the
+// exported recipe: "gulasch.jpg", "gulasch.2.jpg" and so on.
+func ImageNames(recipe *index.Recipe) []string {
+	names := []string{}
+	base := filepath.Base(recipe.Name)
+	for idx, image := range recipe.Data.Images {
+		ext := strings.ToLower(filepath.Ext(image))
+		if idx == 0 {
+			names = append(names, base+ext)
+		} else {
+			names = append(names, fmt.Sprintf("%s.%d%s", base, idx+1, ext))
+		}
+	}
+
+	return names
+}
+
+// ImagePaths returns where the images of `recipe` go when it is exported
+// to `dir`.
+func ImagePaths(recipe *index.Recipe, dir string) []string {
+	paths := []string{}
+	recipeDir := filepath.Dir(filepath.Join(dir, filepath.FromSlash(recipe.Name)))
+	for _, name := range ImageNames(recipe) {
+		paths = append(paths, filepath.Join(recipeDir, name))
+	}
+
+	return paths
+}
+
+// Export writes `recipe` in `format` to `dir`, with its images read from `store`.
+func Export(store storage.Store, format Format, recipe *index.Recipe, dir string) error {
+	path := filepath.Join(dir, filepath.FromSlash(recipe.Name))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	images := ImageNames(recipe)
+	data, err := format.Export(recipe, images)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path+format.Ext(), data, 0600); err != nil {
+		return err
+	}
+
+	for idx, imagePath := range ImagePaths(recipe, dir) {
+		image := recipe.Data.Images[idx]
+		imageData, err := store.Read(image)
+		if err != nil {
+			return fmt.Errorf("Could not read image '%s' (%v)", image, err)
+		}
+
+		if err := ioutil.WriteFile(imagePath, imageData, 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var ingredientAmount = regexp.MustCompile(`^(\d+(?:[.,/]\d+)?(?:\s+\d+/\d+)?)\s*(g|kg|mg|ml|l|cl|dl)?\s+(\S.*)$`)
+
+// splitIngredient splits "250g Mehl" into "250", "g" and "Mehl". Without
+// a leading quantity the whole text is the name.
+func splitIngredient(ingredient string) (quantity, unit, name string) {
+	match := ingredientAmount.FindStringSubmatch(strings.TrimSpace(ingredient))
+	if match == nil {
+		return "", "", strings.TrimSpace(ingredient)
+	}
+
+	return match[1], match[2], match[3]
+}
+
+// isoDuration converts durations like "1h30m" to ISO-8601 ("PT1H30M").
+// Durations that can't be parsed yield "".
+func isoDuration(value string) string {
+	duration, err := time.ParseDuration(strings.Replace(value, " ", "", -1))
+	if err != nil || duration <= 0 {
+		return ""
+	}
+
+	hours, minutes := int(duration.Hours()), int(duration.Minutes())%60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("PT%dH%dM", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("PT%dH", hours)
+	default:
+		return fmt.Sprintf("PT%dM", minutes)
+	}
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"encoding/json"
+
+	"github.com/serztle/nom/index"
+)
+
+// JSONVersion is the version of the JSON schema below. Fields are only
+// ever added to it; renaming or removing one means a new version.
+const JSONVersion = 1
+
+// JSONRecipe is the stable JSON schema of exported recipes:
+//
+//	version          schema version, currently 1
+//	id               name of the recipe in the nom repository
+//	name             display name
+//	category         free text, may be empty
+//	persons          number of persons the amounts are meant for
+//	duration         preparation, cooking and total time like "1h30m"
+//	images           file names of the images next to the json file
+//	cover            the one of images shown for the recipe, "" without images
+//	captions         texts for images, by file name; images without one are left out
+//	ingredients      amounts first, like "250g Mehl"
+//	spices           spices, usually without amount
+//	complementaries  things assumed to be at hand
+//	steps            the instructions, one entry per step
+//	notes            anything else
+//
+// Lists and maps are never null, missing text is "".
+type JSONRecipe struct {
+	Version  int    `json:"version"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Persons  uint   `json:"persons"`
+	Duration struct {
+		Preparation string `json:"preparation"`
+		Cooking     string `json:"cooking"`
+		Total       string `json:"total"`
+	} `json:"duration"`
+	Images          []string          `json:"images"`
+	Cover           string            `json:"cover"`
+	Captions        map[string]string `json:"captions"`
+	Ingredients     []string          `json:"ingredients"`
+	Spices          []string          `json:"spices"`
+	Complementaries []string          `json:"complementaries"`
+	Steps           []string          `json:"steps"`
+	Notes           []string          `json:"notes"`
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Ext() string {
+	return ".json"
+}
+
+func (jsonFormat) Export(recipe *index.Recipe, images []string) ([]byte, error) {
+	list := func(values []string) []string {
+		if values == nil {
+			return []string{}
+		}
+		return values
+	}
+
+	data := JSONRecipe{
+		Version:         JSONVersion,
+		ID:              recipe.Name,
+		Name:            recipe.Data.Name,
+		Category:        recipe.Data.Category,
+		Persons:         recipe.Data.Persons,
+		Images:          list(images),
+		Captions:        map[string]string{},
+		Ingredients:     list(recipe.Data.Ingredients),
+		Spices:          list(recipe.Data.Spices),
+		Complementaries: list(recipe.Data.Complementaries),
+		Steps:           list(recipe.Data.Recipe),
+		Notes:           list(recipe.Data.Notes),
+	}
+	cover := recipe.CoverImage()
+	for idx, image := range recipe.Data.Images {
+		if image == cover && data.Cover == "" {
+			data.Cover = images[idx]
+		}
+
+		if caption := recipe.Caption(image); caption != "" {
+			data.Captions[images[idx]] = caption
+		}
+	}
+
+	data.Duration.Preparation = recipe.Data.Duration.Preparation
+	data.Duration.Cooking = recipe.Data.Duration.Cooking
+	data.Duration.Total = recipe.Data.Duration.Total
+
+	content, err := json.MarshalIndent(data, "", "    ")
+	return append(content, '\n'), err
+}
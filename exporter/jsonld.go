@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+// jsonLD writes a schema.org Recipe, as embedded in web pages.
+type jsonLD struct{}
+
+func (jsonLD) Ext() string {
+	return ".jsonld"
+}
+
+func (jsonLD) Export(recipe *index.Recipe, images []string) ([]byte, error) {
+	data := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    "Recipe",
+		"name":     recipe.Data.Name,
+	}
+
+	if recipe.Data.Category != "" {
+		data["recipeCategory"] = recipe.Data.Category
+	}
+	if recipe.Data.Persons > 0 {
+		data["recipeYield"] = fmt.Sprintf("%d", recipe.Data.Persons)
+	}
+
+	durations := map[string]string{
+		"prepTime":  recipe.Data.Duration.Preparation,
+		"cookTime":  recipe.Data.Duration.Cooking,
+		"totalTime": recipe.Data.Duration.Total,
+	}
+	for key, value := range durations {
+		if duration := isoDuration(value); duration != "" {
+			data[key] = duration
+		}
+	}
+
+	// The cover goes first, as that is the image shown for the recipe.
+	cover := recipe.CoverImage()
+	imageData := []interface{}{}
+	for idx, name := range images {
+		var image interface{} = name
+		if caption := recipe.Caption(recipe.Data.Images[idx]); caption != "" {
+			image = map[string]string{"@type": "ImageObject", "url": name, "caption": caption}
+		}
+
+		if recipe.Data.Images[idx] == cover {
+			imageData = append([]interface{}{image}, imageData...)
+		} else {
+			imageData = append(imageData, image)
+		}
+	}
+
+	if len(imageData) > 0 {
+		data["image"] = imageData
+	}
+
+	ingredients := []string{}
+	ingredients = append(ingredients, recipe.Data.Ingredients...)
+	ingredients = append(ingredients, recipe.Data.Spices...)
+	ingredients = append(ingredients, recipe.Data.Complementaries...)
+	data["recipeIngredient"] = ingredients
+
+	steps := []map[string]string{}
+	for _, step := range recipe.Data.Recipe {
+		steps = append(steps, map[string]string{"@type": "HowToStep", "text": strings.TrimSpace(step)})
+	}
+	data["recipeInstructions"] = steps
+
+	if len(recipe.Data.Notes) > 0 {
+		data["description"] = strings.Join(recipe.Data.Notes, "\n")
+	}
+
+	content, err := json.MarshalIndent(data, "", "    ")
+	return append(content, '\n'), err
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+// markdown writes a recipe for reading, like the web view does.
+type markdown struct{}
+
+func (markdown) Ext() string {
+	return ".md"
+}
+
+func (markdown) Export(recipe *index.Recipe, images []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n\n", recipe.Data.Name)
+
+	facts := []string{}
+	if recipe.Data.Category != "" {
+		facts = append(facts, recipe.Data.Category)
+	}
+	if recipe.Data.Persons > 0 {
+		facts = append(facts, fmt.Sprintf("%d persons", recipe.Data.Persons))
+	}
+
+	durations := [][2]string{
+		{"preparation", recipe.Data.Duration.Preparation},
+		{"cooking", recipe.Data.Duration.Cooking},
+		{"total", recipe.Data.Duration.Total},
+	}
+	for _, duration := range durations {
+		if duration[1] != "" {
+			facts = append(facts, duration[0]+" "+duration[1])
+		}
+	}
+
+	if len(facts) > 0 {
+		fmt.Fprintf(buf, "*%s*\n\n", strings.Join(facts, " · "))
+	}
+
+	for idx, image := range images {
+		alt := recipe.Caption(recipe.Data.Images[idx])
+		if alt == "" {
+			alt = recipe.Data.Name
+		}
+
+		fmt.Fprintf(buf, "![%s](%s)\n\n", alt, image)
+	}
+
+	sections := []struct {
+		title   string
+		entries []string
+		ordered bool
+	}{
+		{"Ingredients", recipe.Data.Ingredients, false},
+		{"Spices", recipe.Data.Spices, false},
+		{"Complementaries", recipe.Data.Complementaries, false},
+		{"Recipe", recipe.Data.Recipe, true},
+		{"Notes", recipe.Data.Notes, false},
+	}
+
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "## %s\n\n", section.title)
+		for idx, entry := range section.entries {
+			entry = strings.Replace(strings.TrimSpace(entry), "\n", "\n   ", -1)
+			if section.ordered {
+				fmt.Fprintf(buf, "%d. %s\n", idx+1, entry)
+			} else {
+				fmt.Fprintf(buf, "- %s\n", entry)
+			}
+		}
+
+		buf.WriteString("\n")
+	}
+
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n'), nil
+}
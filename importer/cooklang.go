@@ -0,0 +1,193 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serztle/nom/index"
+)
+
+var (
+	cookBlockComment = regexp.MustCompile(`(?s)\[-.*?-\]`)
+	cookComment      = regexp.MustCompile(`--.*$`)
+	cookMetadata     = regexp.MustCompile(`^>>\s*([^:]+):\s*(.*)$`)
+	cookSection      = regexp.MustCompile(`^=+\s*(.*?)\s*=*$`)
+	cookToken        = regexp.MustCompile(`([@#~])(?:([^@#~{}\n]*?)\{([^}]*)\}|([^\s@#~{}.,;:!?()]+))`)
+	humanDuration    = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(hours?|hrs?|h|stunden?|std|minutes?|mins?|minuten?|m)`)
+)
+
+// cooklang reads recipes written in Cooklang (https://cooklang.org).
+// An image with the same base name next to the .cook file is taken along.
+type cooklang struct{}
+
+func (cooklang) Match(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".cook"
+}
+
+func (cooklang) Read(imp *Importer, source string) ([]*Result, error) {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	recipe := ParseCooklang(string(content))
+	if recipe.Data.Name == "" {
+		recipe.Data.Name = base
+	}
+
+	result := &Result{Name: Slug(base), Source: source, Recipe: recipe}
+
+	stem := strings.TrimSuffix(source, filepath.Ext(source))
+	result.Images, err = readImages(stem+".jpg", stem+".jpeg", stem+".png")
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Result{result}, nil
+}
+
+// ParseCooklang maps a Cooklang recipe to recipe data. Every paragraph
+// becomes a step; metadata and lines that can't be parsed end up in the notes.
+func ParseCooklang(content string) index.Recipe {
+	recipe := index.Recipe{}
+	content = cookBlockComment.ReplaceAllString(strings.Replace(content, "\r\n", "\n", -1), "")
+	lines := strings.Split(content, "\n")
+
+	// YAML front matter of newer Cooklang versions; only flat keys are understood.
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for idx := 1; idx < len(lines); idx++ {
+			if strings.TrimSpace(lines[idx]) == "---" {
+				lines = lines[idx+1:]
+				break
+			}
+
+			parts := strings.SplitN(lines[idx], ":", 2)
+			if len(parts) == 2 && !strings.HasPrefix(lines[idx], " ") {
+				cooklangMetadata(&recipe, parts[0], parts[1])
+			} else if text := strings.TrimSpace(lines[idx]); text != "" {
+				recipe.Data.Notes = append(recipe.Data.Notes, text)
+			}
+		}
+	}
+
+	step := []string{}
+	flush := func() {
+		if len(step) > 0 {
+			recipe.Data.Recipe = append(recipe.Data.Recipe, strings.Join(step, " "))
+			step = []string{}
+		}
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(cookComment.ReplaceAllString(line, ""))
+		switch {
+		case line == "":
+			flush()
+		case cookMetadata.MatchString(line):
+			match := cookMetadata.FindStringSubmatch(line)
+			cooklangMetadata(&recipe, match[1], match[2])
+		case strings.HasPrefix(line, ">"):
+			recipe.Data.Notes = append(recipe.Data.Notes, strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "="):
+			flush()
+			if section := cookSection.FindStringSubmatch(line)[1]; section != "" {
+				recipe.Data.Recipe = append(recipe.Data.Recipe, section+":")
+			}
+		default:
+			text, ingredients := cooklangLine(line)
+			if strings.ContainsAny(text, "{}") {
+				recipe.Data.Notes = append(recipe.Data.Notes, line)
+				continue
+			}
+
+			for _, ingredient := range ingredients {
+				if !contains(recipe.Data.Ingredients, ingredient) {
+					recipe.Data.Ingredients = append(recipe.Data.Ingredients, ingredient)
+				}
+			}
+
+			step = append(step, text)
+		}
+	}
+
+	flush()
+	return recipe
+}
+
+// cooklangLine replaces ingredients, cookware and timers in `line` by
+// readable text and returns the ingredients it mentions.
+func cooklangLine(line string) (string, []string) {
+	ingredients := []string{}
+	text := cookToken.ReplaceAllStringFunc(line, func(token string) string {
+		match := cookToken.FindStringSubmatch(token)
+		name, amount := strings.TrimSpace(match[2]+match[4]), match[3]
+		quantity, unit := amount, ""
+		if parts := strings.SplitN(amount, "%", 2); len(parts) == 2 {
+			quantity, unit = parts[0], parts[1]
+		}
+
+		switch match[1] {
+		case "@":
+			ingredients = append(ingredients, formatIngredient(quantity, unit, name))
+			return name
+		case "~":
+			return strings.TrimSpace(strings.TrimSpace(quantity) + " " + strings.TrimSpace(unit))
+		default:
+			return name
+		}
+	})
+
+	return strings.Join(strings.Fields(text), " "), ingredients
+}
+
+func cooklangMetadata(recipe *index.Recipe, key, value string) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	switch key {
+	case "title":
+		recipe.Data.Name = value
+	case "servings", "serves", "yield":
+		if number := firstNumber.FindString(value); number != "" {
+			persons, _ := strconv.ParseUint(number, 10, 32)
+			recipe.Data.Persons = uint(persons)
+		}
+	case "tags", "course", "category":
+		recipe.Data.Category = strings.Trim(value, "[]")
+	case "prep time", "prep_time", "time.prep":
+		recipe.Data.Duration.Preparation = parseDuration(value)
+	case "cook time", "cook_time", "time.cook":
+		recipe.Data.Duration.Cooking = parseDuration(value)
+	case "time", "duration", "total time", "total_time":
+		recipe.Data.Duration.Total = parseDuration(value)
+	default:
+		recipe.Data.Notes = append(recipe.Data.Notes, fmt.Sprintf("%s: %s", key, value))
+	}
+}
+
+// parseDuration converts durations like "1 hour 30 minutes" to "1h30m".
+// Anything that can't be parsed is returned unchanged.
+func parseDuration(value string) string {
+	matches := humanDuration.FindAllStringSubmatch(value, -1)
+	if matches == nil {
+		return value
+	}
+
+	var duration time.Duration
+	for _, match := range matches {
+		number, _ := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+		unit := time.Minute
+		if strings.HasPrefix(strings.ToLower(match[2]), "h") || strings.HasPrefix(strings.ToLower(match[2]), "s") {
+			unit = time.Hour
+		}
+
+		duration += time.Duration(number * float64(unit))
+	}
+
+	return FormatDuration(duration)
+}
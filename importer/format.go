@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Format reads recipes stored in one foreign format.
+type Format interface {
+	// Match tells whether the file at `path` looks like this format.
+	Match(path string) bool
+	// Read returns all recipes found in the file or URL `source`.
+	Read(imp *Importer, source string) ([]*Result, error)
+}
+
+// Formats are all known import formats by name.
+var Formats = map[string]Format{
+	"jsonld":     jsonLD{},
+	"cooklang":   cooklang{},
+	"mealmaster": mealMaster{},
+	"nextcloud":  nextcloud{},
+}
+
+// FormatNames returns the names of all known formats, sorted.
+func FormatNames() []string {
+	names := []string{}
+	for name := range Formats {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Failure is a file that could not be imported.
+type Failure struct {
+	Source string
+	Err    error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("%s: %v", f.Source, f.Err)
+}
+
+// detect returns the format of the file at `path`, or nil if it is unknown.
+func detect(path string) Format {
+	for _, name := range FormatNames() {
+		if Formats[name].Match(path) {
+			return Formats[name]
+		}
+	}
+
+	return nil
+}
+
+// Import reads all recipes from `source` in the format called `format`.
+// An empty `format` detects the format of every file by its name; URLs
+// are read as jsonld. If `source` is a directory, all matching files
+// below it are read and the ones that fail are returned as failures.
+func (imp *Importer) Import(format, source string) ([]*Result, []Failure, error) {
+	var chosen Format
+	if format != "" {
+		var ok bool
+		if chosen, ok = Formats[format]; !ok {
+			return nil, nil, fmt.Errorf("Unknown format '%s'; known are %v", format, FormatNames())
+		}
+	}
+
+	if isURL(source) {
+		if chosen == nil {
+			chosen = Formats["jsonld"]
+		}
+
+		results, err := chosen.Read(imp, source)
+		return results, nil, err
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !info.IsDir() {
+		if chosen == nil {
+			if chosen = detect(source); chosen == nil {
+				return nil, nil, fmt.Errorf("Unknown format of '%s'; please give one", source)
+			}
+		}
+
+		results, err := chosen.Read(imp, source)
+		return results, nil, err
+	}
+
+	results, failures := []*Result{}, []Failure{}
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		current := chosen
+		if current == nil {
+			current = detect(path)
+		}
+
+		if current == nil || !current.Match(path) {
+			return nil
+		}
+
+		found, err := current.Read(imp, path)
+		if err != nil {
+			failures = append(failures, Failure{Source: path, Err: err})
+			return nil
+		}
+
+		results = append(results, found...)
+		return nil
+	})
+
+	return results, failures, err
+}
@@ -0,0 +1,180 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+// Image is an image that belongs to an imported recipe.
+type Image struct {
+	// Name is the original file name; only its extension matters.
+	Name string
+	Data []byte
+}
+
+// Result is a recipe read from a foreign format, together with its images.
+type Result struct {
+	// Name is the suggested recipe name; it may be empty.
+	Name   string
+	Source string
+	Recipe index.Recipe
+	Images []Image
+}
+
+// Importer reads recipes from files or URLs.
+type Importer struct {
+	// Client is used for everything fetched over http(s).
+	Client *http.Client
+}
+
+func New(client *http.Client) *Importer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Importer{Client: client}
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Open returns the content of `source`, which is either a URL or a path.
+func (imp *Importer) Open(source string) ([]byte, error) {
+	if !isURL(source) {
+		return ioutil.ReadFile(source)
+	}
+
+	resp, err := imp.Client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching '%s' failed (%s)", source, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// resolve makes `ref` absolute relative to the document at `base`.
+// References in documents fetched from the web always resolve to URLs,
+// so a page can't make us read local files.
+func resolve(base, ref string) (string, error) {
+	if isURL(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return "", err
+		}
+
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+
+		resolved := baseURL.ResolveReference(refURL).String()
+		if !isURL(resolved) {
+			return "", fmt.Errorf("Image '%s' is not on the web", ref)
+		}
+
+		return resolved, nil
+	}
+
+	if isURL(ref) || filepath.IsAbs(ref) {
+		return ref, nil
+	}
+
+	return filepath.Join(filepath.Dir(base), ref), nil
+}
+
+// fetchImages loads all `refs` relative to `base`.
+func (imp *Importer) fetchImages(base string, refs []string) ([]Image, error) {
+	images := []Image{}
+	for _, ref := range refs {
+		source, err := resolve(base, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := imp.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("Loading image '%s' failed (%v)", source, err)
+		}
+
+		name := source
+		if isURL(source) {
+			if sourceURL, err := url.Parse(source); err == nil {
+				name = path.Base(sourceURL.Path)
+			}
+		}
+
+		images = append(images, Image{Name: name, Data: data})
+	}
+
+	return images, nil
+}
+
+// readImages loads all local files in `paths` that exist.
+func readImages(paths ...string) ([]Image, error) {
+	images := []Image{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("Loading image '%s' failed (%v)", path, err)
+		}
+
+		images = append(images, Image{Name: path, Data: data})
+	}
+
+	return images, nil
+}
+
+// metricUnits are written without space after the quantity, like "250g".
+var metricUnits = map[string]bool{
+	"g": true, "kg": true, "mg": true, "ml": true, "l": true, "cl": true, "dl": true,
+}
+
+// formatIngredient writes an ingredient like the ones in recipes:
+// "250g Mehl", "2 Zehen Knoblauch" or "Salz".
+func formatIngredient(quantity, unit, name string) string {
+	quantity, unit, name = strings.TrimSpace(quantity), strings.TrimSpace(unit), strings.TrimSpace(name)
+	switch {
+	case quantity == "":
+		return strings.TrimSpace(unit + " " + name)
+	case metricUnits[strings.ToLower(unit)]:
+		return quantity + unit + " " + name
+	default:
+		return strings.Join(strings.Fields(quantity+" "+unit+" "+name), " ")
+	}
+}
+
+// Slug turns a display name like "Schwäbischer Kartoffelsalat" into a
+// recipe name like "schwaebischer_kartoffelsalat".
+func Slug(name string) string {
+	replacer := strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+	name = replacer.Replace(strings.ToLower(strings.TrimSpace(name)))
+
+	slug := []rune{}
+	for _, char := range name {
+		switch {
+		case char >= 'a' && char <= 'z', char >= '0' && char <= '9':
+			slug = append(slug, char)
+		case len(slug) > 0 && slug[len(slug)-1] != '_':
+			slug = append(slug, '_')
+		}
+	}
+
+	return strings.Trim(string(slug), "_")
+}
@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serztle/nom/index"
+)
+
+var (
+	jsonLDScript = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']application/ld\+json["'][^>]*>(.*?)</script>`)
+	htmlTag      = regexp.MustCompile(`<[^>]*>`)
+	isoDuration  = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	firstNumber  = regexp.MustCompile(`\d+`)
+)
+
+// jsonLD reads the first schema.org Recipe embedded in a web page.
+type jsonLD struct{}
+
+func (jsonLD) Match(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm"
+}
+
+func (jsonLD) Read(imp *Importer, source string) ([]*Result, error) {
+	page, err := imp.Open(source)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := FindJSONLDRecipe(page)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+
+	recipe := MapJSONLDRecipe(data)
+	result := &Result{Name: Slug(recipe.Data.Name), Source: source, Recipe: recipe}
+	result.Images, err = imp.fetchImages(source, jsonLDImages(data["image"]))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Result{result}, nil
+}
+
+// FindJSONLDRecipe returns the first object of @type Recipe in any
+// application/ld+json script of `page`.
+func FindJSONLDRecipe(page []byte) (map[string]interface{}, error) {
+	for _, match := range jsonLDScript.FindAllSubmatch(page, -1) {
+		var doc interface{}
+		if err := json.Unmarshal(match[1], &doc); err != nil {
+			continue
+		}
+
+		if recipe := findRecipe(doc); recipe != nil {
+			return recipe, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No schema.org Recipe found")
+}
+
+func findRecipe(doc interface{}) map[string]interface{} {
+	switch value := doc.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if recipe := findRecipe(item); recipe != nil {
+				return recipe
+			}
+		}
+	case map[string]interface{}:
+		for _, typ := range jsonLDStrings(value["@type"]) {
+			if typ == "Recipe" {
+				return value
+			}
+		}
+
+		return findRecipe(value["@graph"])
+	}
+
+	return nil
+}
+
+// MapJSONLDRecipe maps a schema.org Recipe to recipe data. Images are left out;
+// the description, tools and url end up in the notes.
+func MapJSONLDRecipe(data map[string]interface{}) index.Recipe {
+	recipe := index.Recipe{}
+	recipe.Data.Name = jsonLDString(data["name"])
+	recipe.Data.Category = strings.Join(jsonLDStrings(data["recipeCategory"]), ", ")
+	recipe.Data.Persons = jsonLDYield(data["recipeYield"])
+	recipe.Data.Duration.Preparation = ParseISODuration(jsonLDString(data["prepTime"]))
+	recipe.Data.Duration.Cooking = ParseISODuration(jsonLDString(data["cookTime"]))
+	recipe.Data.Duration.Total = ParseISODuration(jsonLDString(data["totalTime"]))
+	recipe.Data.Ingredients = jsonLDStrings(data["recipeIngredient"])
+	if len(recipe.Data.Ingredients) == 0 {
+		recipe.Data.Ingredients = jsonLDStrings(data["ingredients"])
+	}
+	recipe.Data.Recipe = jsonLDInstructions(data["recipeInstructions"])
+
+	if description := jsonLDString(data["description"]); description != "" {
+		recipe.Data.Notes = append(recipe.Data.Notes, description)
+	}
+	if tools := jsonLDStrings(data["tool"]); len(tools) > 0 {
+		recipe.Data.Notes = append(recipe.Data.Notes, "Tools: "+strings.Join(tools, ", "))
+	}
+	if url, ok := data["url"].(string); ok && url != "" {
+		recipe.Data.Notes = append(recipe.Data.Notes, "Source: "+url)
+	}
+
+	return recipe
+}
+
+// ParseISODuration converts an ISO-8601 duration like "PT1H30M" to "1h30m".
+// Anything that can't be parsed is returned unchanged.
+func ParseISODuration(value string) string {
+	match := isoDuration.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if match == nil || value == "" {
+		return value
+	}
+
+	number := func(text string) float64 {
+		n, _ := strconv.ParseFloat(text, 64)
+		return n
+	}
+
+	duration := time.Duration(
+		(number(match[1])*24*3600 + number(match[2])*3600 + number(match[3])*60 + number(match[4])) * float64(time.Second),
+	)
+
+	return FormatDuration(duration)
+}
+
+// FormatDuration formats `d` like the durations in recipes: "2h", "45m", "1h30m".
+func FormatDuration(d time.Duration) string {
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+func cleanText(text string) string {
+	text = html.UnescapeString(htmlTag.ReplaceAllString(text, " "))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func jsonLDString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return cleanText(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case []interface{}:
+		if len(typed) > 0 {
+			return jsonLDString(typed[0])
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "url", "@id"} {
+			if text := jsonLDString(typed[key]); text != "" {
+				return text
+			}
+		}
+	}
+
+	return ""
+}
+
+func jsonLDStrings(value interface{}) []string {
+	values := []string{}
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	for _, item := range items {
+		if text := jsonLDString(item); text != "" {
+			values = append(values, text)
+		}
+	}
+
+	return values
+}
+
+func jsonLDYield(value interface{}) uint {
+	for _, text := range jsonLDStrings(value) {
+		if number := firstNumber.FindString(text); number != "" {
+			persons, _ := strconv.ParseUint(number, 10, 32)
+			return uint(persons)
+		}
+	}
+
+	return 0
+}
+
+// jsonLDInstructions flattens text, HowToStep and HowToSection instructions.
+func jsonLDInstructions(value interface{}) []string {
+	steps := []string{}
+	switch typed := value.(type) {
+	case string:
+		for _, line := range strings.Split(html.UnescapeString(typed), "\n") {
+			if line = cleanText(line); line != "" {
+				steps = append(steps, line)
+			}
+		}
+	case []interface{}:
+		for _, item := range typed {
+			steps = append(steps, jsonLDInstructions(item)...)
+		}
+	case map[string]interface{}:
+		if items, ok := typed["itemListElement"]; ok {
+			return jsonLDInstructions(items)
+		}
+
+		if text := jsonLDString(typed); text != "" {
+			steps = append(steps, text)
+		}
+	}
+
+	return steps
+}
+
+func jsonLDImages(value interface{}) []string {
+	images := []string{}
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	for _, item := range items {
+		image := ""
+		switch typed := item.(type) {
+		case string:
+			image = typed
+		case map[string]interface{}:
+			image = jsonLDString(typed["url"])
+			if image == "" {
+				image = jsonLDString(typed["contentUrl"])
+			}
+		}
+
+		if image != "" && !contains(images, image) {
+			images = append(images, image)
+		}
+	}
+
+	return images
+}
+
+func contains(values []string, value string) bool {
+	for _, other := range values {
+		if other == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/serztle/nom/index"
+)
+
+var (
+	mmBegin      = regexp.MustCompile(`^(MMMMM|-----).*Meal-Master`)
+	mmEnd        = regexp.MustCompile(`^(MMMMM|-----)\s*$`)
+	mmSection    = regexp.MustCompile(`^(?:MMMMM|-----)-*\s*(.*?)\s*-*$`)
+	mmHeader     = regexp.MustCompile(`^\s*(Title|Categories|Yield|Servings):\s*(.*)$`)
+	mmIngredient = regexp.MustCompile(`^([ \d/.-]{7}) ([ a-zA-Z]{2}) (\S.*)$`)
+)
+
+// mmUnits maps the two letter units of MealMaster to readable ones.
+var mmUnits = map[string]string{
+	"x": "", "ea": "", "sm": "small", "md": "medium", "lg": "large",
+	"cn": "can", "pk": "package", "pn": "pinch", "dr": "drop", "ds": "dash",
+	"ct": "carton", "bn": "bunch", "sl": "slice", "t": "tsp", "ts": "tsp",
+	"T": "tbsp", "tb": "tbsp", "fl": "fl oz", "c": "cup", "pt": "pint",
+	"qt": "quart", "ga": "gallon", "oz": "oz", "lb": "lb", "ml": "ml",
+	"cb": "ccm", "cl": "cl", "dl": "dl", "l": "l", "mg": "mg", "cg": "cg",
+	"dg": "dg", "g": "g", "kg": "kg",
+}
+
+// mealMaster reads MealMaster export files, which may hold many recipes.
+type mealMaster struct{}
+
+func (mealMaster) Match(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".mmf" || ext == ".mm"
+}
+
+func (mealMaster) Read(imp *Importer, source string) ([]*Result, error) {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	recipes := ParseMealMaster(string(content))
+	if len(recipes) == 0 {
+		return nil, fmt.Errorf("No MealMaster recipe found")
+	}
+
+	results := []*Result{}
+	for _, recipe := range recipes {
+		results = append(results, &Result{
+			Name:   Slug(recipe.Data.Name),
+			Source: source,
+			Recipe: recipe,
+		})
+	}
+
+	return results, nil
+}
+
+// ParseMealMaster maps all recipes in a MealMaster file to recipe data.
+// Ingredient lines that don't fit the column layout end up in the notes.
+func ParseMealMaster(content string) []index.Recipe {
+	recipes := []index.Recipe{}
+	lines := strings.Split(strings.Replace(content, "\r\n", "\n", -1), "\n")
+
+	for idx := 0; idx < len(lines); idx++ {
+		if !mmBegin.MatchString(lines[idx]) {
+			continue
+		}
+
+		end := idx + 1
+		for end < len(lines) && !mmEnd.MatchString(lines[end]) && !mmBegin.MatchString(lines[end]) {
+			end++
+		}
+
+		recipes = append(recipes, parseMealMasterRecipe(lines[idx+1:end]))
+		idx = end - 1
+	}
+
+	return recipes
+}
+
+// isMMContinuation tells if the ingredient column `text` continues the
+// ingredient above it.
+func isMMContinuation(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "-") && strings.HasPrefix(text, "  ")
+}
+
+func parseMealMasterRecipe(lines []string) index.Recipe {
+	recipe := index.Recipe{}
+	idx := 0
+
+	// Header lines up to the first blank line after them.
+	for ; idx < len(lines); idx++ {
+		line := strings.TrimSpace(lines[idx])
+		if match := mmHeader.FindStringSubmatch(lines[idx]); match != nil {
+			switch match[1] {
+			case "Title":
+				recipe.Data.Name = strings.TrimSpace(match[2])
+			case "Categories":
+				recipe.Data.Category = strings.TrimSpace(match[2])
+			default:
+				if number := firstNumber.FindString(match[2]); number != "" {
+					persons, _ := strconv.ParseUint(number, 10, 32)
+					recipe.Data.Persons = uint(persons)
+				}
+			}
+		} else if line == "" && recipe.Data.Name != "" {
+			break
+		} else if line != "" {
+			recipe.Data.Notes = append(recipe.Data.Notes, line)
+		}
+	}
+
+	// Ingredients, possibly in two columns, until the first line of text.
+	// Lines starting with "-" continue the last ingredient of their column.
+	lastInColumn := map[int]int{}
+	for ; idx < len(lines); idx++ {
+		line := strings.TrimRight(lines[idx], " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "MMMMM") || strings.HasPrefix(line, "-----") {
+			if section := mmSection.FindStringSubmatch(line)[1]; section != "" {
+				recipe.Data.Notes = append(recipe.Data.Notes, "Section: "+section)
+			}
+			continue
+		}
+
+		// Columns are counted in characters, not bytes.
+		columns := []string{line}
+		if runes := []rune(line); len(runes) > 41 {
+			left, right := string(runes[:41]), string(runes[41:])
+			if mmIngredient.MatchString(right) || isMMContinuation(right) {
+				columns = []string{strings.TrimRight(left, " "), right}
+			}
+		}
+
+		if !mmIngredient.MatchString(columns[0]) && !isMMContinuation(columns[0]) && strings.TrimSpace(columns[0]) != "" {
+			break
+		}
+
+		for column, text := range columns {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+
+			if isMMContinuation(text) {
+				last, ok := lastInColumn[column]
+				if !ok {
+					last = len(recipe.Data.Ingredients) - 1
+				}
+
+				if last >= 0 {
+					recipe.Data.Ingredients[last] += " " + strings.TrimSpace(strings.TrimSpace(text)[1:])
+				}
+				continue
+			}
+
+			match := mmIngredient.FindStringSubmatch(text)
+			if match == nil {
+				recipe.Data.Notes = append(recipe.Data.Notes, strings.TrimSpace(text))
+				continue
+			}
+
+			unit := strings.TrimSpace(match[2])
+			if readable, ok := mmUnits[unit]; ok {
+				unit = readable
+			}
+
+			lastInColumn[column] = len(recipe.Data.Ingredients)
+			recipe.Data.Ingredients = append(recipe.Data.Ingredients, formatIngredient(match[1], unit, match[3]))
+		}
+	}
+
+	// Directions: every paragraph becomes a step.
+	step := []string{}
+	for ; idx <= len(lines); idx++ {
+		line := ""
+		if idx < len(lines) {
+			line = strings.TrimSpace(lines[idx])
+		}
+
+		if line != "" {
+			step = append(step, line)
+			continue
+		}
+
+		if len(step) > 0 {
+			recipe.Data.Recipe = append(recipe.Data.Recipe, strings.Join(step, " "))
+			step = []string{}
+		}
+	}
+
+	return recipe
+}
@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// nextcloud reads a recipe folder of the Nextcloud Cookbook app:
+// a schema.org recipe.json and the image full.jpg next to it.
+type nextcloud struct{}
+
+func (nextcloud) Match(path string) bool {
+	return filepath.Base(path) == "recipe.json"
+}
+
+func (nextcloud) Read(imp *Importer, source string) ([]*Result, error) {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("Could not parse json (%v)", err)
+	}
+
+	recipe := MapJSONLDRecipe(data)
+	name := Slug(recipe.Data.Name)
+	if name == "" {
+		name = Slug(filepath.Base(filepath.Dir(source)))
+	}
+
+	result := &Result{Name: name, Source: source, Recipe: recipe}
+	result.Images, err = readImages(filepath.Join(filepath.Dir(source), "full.jpg"))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Result{result}, nil
+}
@@ -0,0 +1,29 @@
+package index
+
+// Archived recipes stay in the store and the index, but are left out of
+// plans, the web overview and the default recipe list. The index keeps
+// the state as the value of each recipe: true while it is in use, false
+// once it is archived.
+
+// RecipeArchived tells if `name` is part of the index but archived.
+func (i *Index) RecipeArchived(name string) bool {
+	active, ok := i.Recipes[name]
+	return ok && !active
+}
+
+// RecipeArchive archives `name`, or brings it back if `archived` is false.
+func (i *Index) RecipeArchive(name string, archived bool) {
+	i.Recipes[name] = !archived
+}
+
+// Active returns a new index over the same store without archived recipes.
+func (i *Index) Active() *Index {
+	active := NewIndex(i.store)
+	for name := range i.Recipes {
+		if !i.RecipeArchived(name) {
+			active.RecipeAdd(name)
+		}
+	}
+
+	return active
+}
@@ -0,0 +1,179 @@
+package index
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sort"
+
+	"github.com/serztle/nom/storage"
+)
+
+const (
+	// CollectionsPath is where the collections live inside the store.
+	CollectionsPath = ".collections"
+)
+
+// Collections are named groups of recipes from the index.
+// They only reference recipes by name; no files are moved.
+type Collections struct {
+	store storage.Store
+	Sets  map[string][]string
+}
+
+func NewCollections(store storage.Store) *Collections {
+	return &Collections{
+		store: store,
+		Sets:  make(map[string][]string),
+	}
+}
+
+func (c *Collections) Filename() string {
+	return CollectionsPath
+}
+
+// Parse reads all collections. A missing file means no collections.
+func (c *Collections) Parse() error {
+	if !c.store.Exists(CollectionsPath) {
+		return nil
+	}
+
+	content, err := c.store.Read(CollectionsPath)
+	if err != nil {
+		return fmt.Errorf("Reading collections %s (%v)!", CollectionsPath, err)
+	}
+
+	return c.Unmarshal(content)
+}
+
+// Unmarshal fills the collections from yaml `content`.
+func (c *Collections) Unmarshal(content []byte) error {
+	if err := yaml.Unmarshal(content, c.Sets); err != nil {
+		return fmt.Errorf("Seems like collections %s is not valid yaml (%v)!", CollectionsPath, err)
+	}
+
+	return nil
+}
+
+func (c *Collections) Save() error {
+	content, err := yaml.Marshal(c.Sets)
+	if err != nil {
+		return fmt.Errorf("Making yaml for collections %s (%v)!", CollectionsPath, err)
+	}
+
+	if err := c.store.Write(CollectionsPath, content); err != nil {
+		return fmt.Errorf("Writing collections to %s (%v)!", CollectionsPath, err)
+	}
+
+	return nil
+}
+
+// Names returns the sorted names of all collections.
+func (c *Collections) Names() []string {
+	names := []string{}
+	for name := range c.Sets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (c *Collections) Exists(name string) bool {
+	_, ok := c.Sets[name]
+	return ok
+}
+
+func (c *Collections) Create(name string) {
+	if !c.Exists(name) {
+		c.Sets[name] = []string{}
+	}
+}
+
+func (c *Collections) Delete(name string) {
+	delete(c.Sets, name)
+}
+
+// Contains tells if `recipe` is part of the collection `name`.
+func (c *Collections) Contains(name, recipe string) bool {
+	for _, member := range c.Sets[name] {
+		if member == recipe {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RecipeAdd adds `recipe` to the collection `name` unless it is already there.
+func (c *Collections) RecipeAdd(name, recipe string) {
+	if !c.Contains(name, recipe) {
+		c.Sets[name] = append(c.Sets[name], recipe)
+	}
+}
+
+// RecipeRemove removes `recipe` from the collection `name`.
+func (c *Collections) RecipeRemove(name, recipe string) {
+	members := []string{}
+	for _, member := range c.Sets[name] {
+		if member != recipe {
+			members = append(members, member)
+		}
+	}
+
+	c.Sets[name] = members
+}
+
+// RecipeForget removes `recipe` from every collection.
+func (c *Collections) RecipeForget(recipe string) {
+	for name := range c.Sets {
+		c.RecipeRemove(name, recipe)
+	}
+}
+
+// RecipeRename replaces `recipe` by `newName` in every collection.
+func (c *Collections) RecipeRename(recipe, newName string) {
+	for name, members := range c.Sets {
+		if !c.Contains(name, recipe) {
+			continue
+		}
+
+		renamed := []string{}
+		for _, member := range members {
+			if member == recipe {
+				member = newName
+			}
+
+			if !containsValue(renamed, member) {
+				renamed = append(renamed, member)
+			}
+		}
+
+		c.Sets[name] = renamed
+	}
+}
+
+// Filter returns a new index over the same store that only contains the
+// recipes of collection `name` which are also part of `idx`.
+func (c *Collections) Filter(idx *Index, name string) (*Index, error) {
+	if !c.Exists(name) {
+		return nil, fmt.Errorf("No collection found with the name '%s'", name)
+	}
+
+	filtered := NewIndex(idx.Store())
+	for _, recipe := range c.Sets[name] {
+		if idx.RecipeExists(recipe) {
+			filtered.Recipes[recipe] = idx.Recipes[recipe]
+		}
+	}
+
+	return filtered, nil
+}
+
+func (c *Collections) String() (string, error) {
+	content, err := yaml.Marshal(c.Sets)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,74 @@
+package index
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+
+	"github.com/serztle/nom/storage"
+)
+
+const (
+	// ConfigPath is where the repository configuration lives inside the store.
+	ConfigPath = ".nomconfig"
+)
+
+// Config holds settings shared by everybody using the repository.
+type Config struct {
+	store storage.Store
+
+	// Messages maps an operation (like "edit") to a commit message template.
+	Messages map[string]string `yaml:"messages,omitempty"`
+
+	// Images says how images are normalized when they are added.
+	Images ImageConfig `yaml:"images,omitempty"`
+
+	// Plan holds the rules for the plan subcommand.
+	Plan PlanConfig `yaml:"plan,omitempty"`
+}
+
+// ImageConfig is the `images` section of the repository config.
+type ImageConfig struct {
+	// MaxSize is the longest edge in pixels; 0 keeps the size.
+	MaxSize int `yaml:"max-size"`
+	// Format is "jpeg", "png" or "webp" (lossless) to convert all images,
+	// "" to keep their format.
+	Format string `yaml:"format,omitempty"`
+	// Quality is used for jpeg, from 1 to 100.
+	Quality int `yaml:"quality,omitempty"`
+}
+
+const (
+	// DefaultImageMaxSize is large enough for any screen, and small enough for git.
+	DefaultImageMaxSize = 1600
+	// DefaultImageQuality is used for jpeg unless configured.
+	DefaultImageQuality = 85
+)
+
+func NewConfig(store storage.Store) *Config {
+	return &Config{
+		store:    store,
+		Messages: make(map[string]string),
+		Images: ImageConfig{
+			MaxSize: DefaultImageMaxSize,
+			Quality: DefaultImageQuality,
+		},
+	}
+}
+
+// Parse reads the configuration. A missing file means defaults everywhere.
+func (c *Config) Parse() error {
+	if !c.store.Exists(ConfigPath) {
+		return nil
+	}
+
+	content, err := c.store.Read(ConfigPath)
+	if err != nil {
+		return fmt.Errorf("Reading config %s (%v)!", ConfigPath, err)
+	}
+
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return fmt.Errorf("Seems like config %s is not valid yaml (%v)!", ConfigPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package index
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"time"
+
+	"github.com/serztle/nom/storage"
+)
+
+const (
+	// CookedPath is where the last cooking date of each recipe is kept.
+	CookedPath = ".cooked"
+
+	// CookedFormat is how the cooking dates are written.
+	CookedFormat = "2006-01-02"
+)
+
+// Cooked remembers when each recipe was cooked the last time.
+type Cooked struct {
+	store storage.Store
+	Dates map[string]string
+}
+
+func NewCooked(store storage.Store) *Cooked {
+	return &Cooked{
+		store: store,
+		Dates: make(map[string]string),
+	}
+}
+
+func (c *Cooked) Filename() string {
+	return CookedPath
+}
+
+// Parse reads all cooking dates. A missing file means nothing was cooked.
+func (c *Cooked) Parse() error {
+	if !c.store.Exists(CookedPath) {
+		return nil
+	}
+
+	content, err := c.store.Read(CookedPath)
+	if err != nil {
+		return fmt.Errorf("Reading cooking dates %s (%v)!", CookedPath, err)
+	}
+
+	if err := yaml.Unmarshal(content, c.Dates); err != nil {
+		return fmt.Errorf("Seems like cooking dates %s are not valid yaml (%v)!", CookedPath, err)
+	}
+
+	return nil
+}
+
+func (c *Cooked) Save() error {
+	content, err := yaml.Marshal(c.Dates)
+	if err != nil {
+		return fmt.Errorf("Making yaml for cooking dates %s (%v)!", CookedPath, err)
+	}
+
+	if err := c.store.Write(CookedPath, content); err != nil {
+		return fmt.Errorf("Writing cooking dates to %s (%v)!", CookedPath, err)
+	}
+
+	return nil
+}
+
+// Last returns when `recipe` was cooked the last time, if ever.
+func (c *Cooked) Last(recipe string) (time.Time, bool) {
+	date, err := time.Parse(CookedFormat, c.Dates[recipe])
+	return date, err == nil
+}
+
+// Mark remembers that `recipe` was cooked on `date`.
+func (c *Cooked) Mark(recipe string, date time.Time) {
+	c.Dates[recipe] = date.Format(CookedFormat)
+}
+
+// RecipeRename moves the cooking date of `recipe` over to `newName`.
+func (c *Cooked) RecipeRename(recipe, newName string) {
+	if date, ok := c.Dates[recipe]; ok {
+		delete(c.Dates, recipe)
+		c.Dates[newName] = date
+	}
+}
+
+// RecipeForget drops the cooking date of `recipe`.
+func (c *Cooked) RecipeForget(recipe string) {
+	delete(c.Dates, recipe)
+}
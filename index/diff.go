@@ -0,0 +1,194 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field is a single named part of a recipe's data.
+// Scalar fields always have exactly one value.
+type Field struct {
+	Name   string
+	List   bool
+	Values []string
+}
+
+// Fields returns all fields of the recipe in a stable order.
+func (r *Recipe) Fields() []Field {
+	scalar := func(name, value string) Field {
+		return Field{Name: name, Values: []string{value}}
+	}
+
+	list := func(name string, values []string) Field {
+		return Field{Name: name, List: true, Values: values}
+	}
+
+	return []Field{
+		scalar("name", r.Data.Name),
+		scalar("category", r.Data.Category),
+		scalar("persons", fmt.Sprintf("%d", r.Data.Persons)),
+		scalar("duration.preparation", r.Data.Duration.Preparation),
+		scalar("duration.cooking", r.Data.Duration.Cooking),
+		scalar("duration.total", r.Data.Duration.Total),
+		list("images", r.Data.Images),
+		scalar("cover", r.Data.Cover),
+		list("captions", r.captionList()),
+		list("ingredients", r.Data.Ingredients),
+		list("spices", r.Data.Spices),
+		list("complementaries", r.Data.Complementaries),
+		list("recipe", r.Data.Recipe),
+		list("notes", r.Data.Notes),
+	}
+}
+
+// DiffLine is one line of a list diff. Op is one of ' ', '-' or '+'.
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// Change describes how a single field differs between two recipes.
+type Change struct {
+	Field string
+	List  bool
+	Old   string
+	New   string
+	Lines []DiffLine
+}
+
+// Added returns the number of list entries that were added.
+func (c Change) Added() int {
+	return c.count('+')
+}
+
+// Removed returns the number of list entries that were removed.
+func (c Change) Removed() int {
+	return c.count('-')
+}
+
+func (c Change) count(op byte) int {
+	n := 0
+	for _, line := range c.Lines {
+		if line.Op == op {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (c Change) String() string {
+	if !c.List {
+		return fmt.Sprintf("%s: %s → %s", c.Field, c.Old, c.New)
+	}
+
+	lines := []string{c.Field + ":"}
+	for _, line := range c.Lines {
+		if line.Op != ' ' {
+			lines = append(lines, fmt.Sprintf("  %c %s", line.Op, line.Text))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Summary describes `changes` in a few words, like "persons 4→6, +2 ingredients".
+func Summary(changes []Change) string {
+	parts := []string{}
+	for _, change := range changes {
+		if !change.List {
+			parts = append(parts, fmt.Sprintf("%s %s→%s", change.Field, change.Old, change.New))
+			continue
+		}
+
+		if added := change.Added(); added > 0 {
+			parts = append(parts, fmt.Sprintf("+%d %s", added, change.Field))
+		}
+
+		if removed := change.Removed(); removed > 0 {
+			parts = append(parts, fmt.Sprintf("-%d %s", removed, change.Field))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// DiffLists computes a minimal line diff from `a` to `b`.
+func DiffLists(a, b []string) []DiffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{'+', b[j]})
+	}
+
+	return lines
+}
+
+// Diff returns all fields that differ between `old` and `new`.
+func Diff(old, new *Recipe) []Change {
+	changes := []Change{}
+	newFields := new.Fields()
+	for idx, oldField := range old.Fields() {
+		newField := newFields[idx]
+		if !oldField.List {
+			if oldField.Values[0] != newField.Values[0] {
+				changes = append(changes, Change{
+					Field: oldField.Name,
+					Old:   oldField.Values[0],
+					New:   newField.Values[0],
+				})
+			}
+
+			continue
+		}
+
+		change := Change{
+			Field: oldField.Name,
+			List:  true,
+			Lines: DiffLists(oldField.Values, newField.Values),
+		}
+
+		if change.Added() > 0 || change.Removed() > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,136 @@
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var fieldPath = regexp.MustCompile(`^([a-z.]+)(?:\[(\d+|\+|-)\])?$`)
+
+// field returns the field called `name` of the recipe.
+func (r *Recipe) field(name string) (Field, error) {
+	for _, field := range r.Fields() {
+		if field.Name == name {
+			return field, nil
+		}
+	}
+
+	return Field{}, fmt.Errorf("Unknown field '%s'", name)
+}
+
+// parseFieldPath splits paths like "ingredients[2]" into the field and
+// the part of it: "" for all of it, "+", "-" or a position counting from 1.
+func (r *Recipe) parseFieldPath(path string) (Field, string, error) {
+	match := fieldPath.FindStringSubmatch(path)
+	if match == nil {
+		return Field{}, "", fmt.Errorf("Field paths look like persons, duration.total or ingredients[2], not '%s'", path)
+	}
+
+	field, err := r.field(match[1])
+	if err != nil {
+		return Field{}, "", err
+	}
+
+	if match[2] != "" && !field.List {
+		return Field{}, "", fmt.Errorf("'%s' is not a list", field.Name)
+	}
+
+	return field, match[2], nil
+}
+
+// position checks that `part` is a position in `field` and returns its index.
+func position(field Field, part string) (int, error) {
+	pos, err := strconv.Atoi(part)
+	if err != nil || pos < 1 || pos > len(field.Values) {
+		return 0, fmt.Errorf("%s has no entry %s", field.Name, part)
+	}
+
+	return pos - 1, nil
+}
+
+// Get returns the values at `path`, like "persons", "ingredients" or "recipe[2]".
+func (r *Recipe) Get(path string) ([]string, error) {
+	field, part, err := r.parseFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch part {
+	case "":
+		return field.Values, nil
+	case "+", "-":
+		return nil, fmt.Errorf("Can't get '%s'", path)
+	}
+
+	idx, err := position(field, part)
+	if err != nil {
+		return nil, err
+	}
+
+	return field.Values[idx : idx+1], nil
+}
+
+// Set changes the recipe at `path` to `values` and validates the changed field.
+// Scalars and list entries like "recipe[2]" take exactly one value, whole
+// lists any number. "ingredients[+]" appends values, "ingredients[-]"
+// removes the entries given by position or text.
+func (r *Recipe) Set(path string, values []string) error {
+	field, part, err := r.parseFieldPath(path)
+	if err != nil {
+		return err
+	}
+
+	current := append([]string{}, field.Values...)
+	single := !field.List || (part != "" && part != "+" && part != "-")
+	if single && len(values) != 1 {
+		return fmt.Errorf("%s takes exactly one value", path)
+	}
+
+	switch part {
+	case "":
+		current = values
+	case "+":
+		current = append(current, values...)
+	case "-":
+		remove := make(map[int]bool)
+		for _, value := range values {
+			idx, err := position(field, value)
+			if err != nil {
+				idx = -1
+				for other, entry := range field.Values {
+					if entry == value {
+						idx = other
+						break
+					}
+				}
+			}
+
+			if idx < 0 {
+				return fmt.Errorf("%s has no entry '%s'", field.Name, value)
+			}
+
+			remove[idx] = true
+		}
+
+		current = []string{}
+		for idx, entry := range field.Values {
+			if !remove[idx] {
+				current = append(current, entry)
+			}
+		}
+	default:
+		idx, err := position(field, part)
+		if err != nil {
+			return err
+		}
+
+		current[idx] = values[0]
+	}
+
+	if err := r.setField(field.Name, current); err != nil {
+		return err
+	}
+
+	return r.validateField(field.Name)
+}
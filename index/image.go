@@ -0,0 +1,166 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// ImagesDir is where all images live inside the store.
+	ImagesDir = ".images"
+)
+
+// ImagePath returns where an image with the content `data` is stored:
+// Its content hash plus the extension of its original `filename`.
+// The same image therefore always ends up at the same place.
+func ImagePath(data []byte, filename string) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return path.Join(ImagesDir, hash[:2], hash+strings.ToLower(filepath.Ext(filename)))
+}
+
+// ReferencedImages returns all images used by any recipe in the index.
+func (i *Index) ReferencedImages() (map[string]bool, error) {
+	images := make(map[string]bool)
+	for name := range i.Recipes {
+		recipe := NewRecipe(name)
+		if err := recipe.Load(i.store); err != nil {
+			return nil, err
+		}
+
+		for _, image := range recipe.Data.Images {
+			images[image] = true
+		}
+	}
+
+	return images, nil
+}
+
+// CoverImage is the image shown for the recipe in overviews: the chosen
+// cover if it is still one of its images, the first image otherwise.
+func (r *Recipe) CoverImage() string {
+	for _, image := range r.Data.Images {
+		if image == r.Data.Cover {
+			return image
+		}
+	}
+
+	if len(r.Data.Images) > 0 {
+		return r.Data.Images[0]
+	}
+
+	return ""
+}
+
+// Caption returns the caption of `image`, or "".
+func (r *Recipe) Caption(image string) string {
+	return r.Data.Captions[image]
+}
+
+// SetCaption sets the caption of `image`; an empty `caption` removes it.
+func (r *Recipe) SetCaption(image, caption string) {
+	if caption == "" {
+		delete(r.Data.Captions, image)
+		if len(r.Data.Captions) == 0 {
+			r.Data.Captions = nil
+		}
+
+		return
+	}
+
+	if r.Data.Captions == nil {
+		r.Data.Captions = make(map[string]string)
+	}
+
+	r.Data.Captions[image] = caption
+}
+
+// captionList returns the captions as "<image>: <caption>" in image order,
+// followed by captions of images the recipe does not have (anymore).
+func (r *Recipe) captionList() []string {
+	captions := []string{}
+	seen := make(map[string]bool)
+	for _, image := range r.Data.Images {
+		if caption, ok := r.Data.Captions[image]; ok && !seen[image] {
+			captions = append(captions, image+": "+caption)
+			seen[image] = true
+		}
+	}
+
+	rest := []string{}
+	for image, caption := range r.Data.Captions {
+		if !seen[image] {
+			rest = append(rest, image+": "+caption)
+		}
+	}
+
+	sort.Strings(rest)
+	return append(captions, rest...)
+}
+
+// setCaptionList is the reverse of captionList.
+func (r *Recipe) setCaptionList(captions []string) error {
+	r.Data.Captions = nil
+	for _, entry := range captions {
+		parts := strings.SplitN(entry, ": ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("captions must look like '<image>: <caption>', not '%s'", entry)
+		}
+
+		r.SetCaption(parts[0], parts[1])
+	}
+
+	return nil
+}
+
+// Rename gives the recipe the name `newName`. Images in the old per-recipe
+// image dir (see ImageDir) are pointed to the new one; the returned map
+// tells which old path moves where. Images stored by content stay put.
+func (r *Recipe) Rename(newName string) map[string]string {
+	oldDir := r.ImageDir() + "/"
+	r.Name = newName
+	newDir := r.ImageDir() + "/"
+
+	moved := make(map[string]string)
+	for _, image := range r.Data.Images {
+		if strings.HasPrefix(image, oldDir) && !isContentPath(image) {
+			moved[image] = newDir + strings.TrimPrefix(image, oldDir)
+		}
+	}
+
+	for idx, image := range r.Data.Images {
+		if newPath, ok := moved[image]; ok {
+			r.Data.Images[idx] = newPath
+		}
+	}
+
+	if newPath, ok := moved[r.Data.Cover]; ok {
+		r.Data.Cover = newPath
+	}
+
+	for image, caption := range r.Data.Captions {
+		if newPath, ok := moved[image]; ok {
+			delete(r.Data.Captions, image)
+			r.Data.Captions[newPath] = caption
+		}
+	}
+
+	return moved
+}
+
+// isContentPath tells if `image` looks like a path made by ImagePath.
+func isContentPath(image string) bool {
+	dir, file := path.Split(image)
+	hash := strings.TrimSuffix(file, path.Ext(file))
+	if len(hash) != 2*sha256.Size || dir != path.Join(ImagesDir, hash[:2])+"/" {
+		return false
+	}
+
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
@@ -3,54 +3,56 @@ package index
 import (
 	"fmt"
 	"gopkg.in/yaml.v2"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+
+	"github.com/serztle/nom/storage"
+)
+
+const (
+	// IndexPath is where the index lives inside the store.
+	IndexPath = ".nom"
 )
 
 type Index struct {
-	indexPath string
-	repoDir   string
-	Recipes   map[string]bool
+	store   storage.Store
+	Recipes map[string]bool
 }
 
-func NewIndex(dir string) *Index {
+func NewIndex(store storage.Store) *Index {
 	return &Index{
-		indexPath: filepath.Join(dir, ".nom"),
-		repoDir:   dir,
-		Recipes:   make(map[string]bool),
+		store:   store,
+		Recipes: make(map[string]bool),
 	}
 }
 
-func (i *Index) RepoDir() string {
-	return i.repoDir
+// Store returns the storage the index and its recipes live in.
+func (i *Index) Store() storage.Store {
+	return i.store
 }
 
 func (i *Index) Filename() string {
-	return filepath.Base(i.indexPath)
+	return IndexPath
 }
 
 func (i *Index) Parse() error {
-	content, err := ioutil.ReadFile(i.indexPath)
+	content, err := i.store.Read(IndexPath)
 	if err != nil {
-		return fmt.Errorf("Reading index %s (%v)!", i.indexPath, err)
+		return fmt.Errorf("Reading index %s (%v)!", IndexPath, err)
 	}
 
+	return i.Unmarshal(content)
+}
+
+// Unmarshal fills the index from yaml `content`.
+func (i *Index) Unmarshal(content []byte) error {
 	if err := yaml.Unmarshal(content, i.Recipes); err != nil {
-		return fmt.Errorf("Seems like index %s is not valid yaml (%v)!", i.indexPath, err)
+		return fmt.Errorf("Seems like index %s is not valid yaml (%v)!", IndexPath, err)
 	}
 
 	return nil
 }
 
 func (i *Index) Exists() bool {
-	if stat, err := os.Stat(i.indexPath); err != nil {
-		return false
-	} else if !stat.Mode().IsRegular() {
-		return false
-	} else {
-		return true
-	}
+	return i.store.Exists(IndexPath)
 }
 
 func (i *Index) RecipeExists(name string) bool {
@@ -58,8 +60,11 @@ func (i *Index) RecipeExists(name string) bool {
 	return ok
 }
 
+// RecipeAdd adds `name` to the index; an archived recipe stays archived.
 func (i *Index) RecipeAdd(name string) {
-	i.Recipes[name] = true
+	if !i.RecipeExists(name) {
+		i.Recipes[name] = true
+	}
 }
 
 func (i *Index) RecipeRemove(name string) {
@@ -69,11 +74,11 @@ func (i *Index) RecipeRemove(name string) {
 func (i *Index) Save() error {
 	content, err := yaml.Marshal(i.Recipes)
 	if err != nil {
-		return fmt.Errorf("Making yaml for index %s (%v)!", i.indexPath, err)
+		return fmt.Errorf("Making yaml for index %s (%v)!", IndexPath, err)
 	}
 
-	if err := ioutil.WriteFile(i.indexPath, content, 0666); err != nil {
-		return fmt.Errorf("Writing index to %s (%v)!", i.indexPath, err)
+	if err := i.store.Write(IndexPath, content); err != nil {
+		return fmt.Errorf("Writing index to %s (%v)!", IndexPath, err)
 	}
 
 	return nil
@@ -0,0 +1,267 @@
+package index
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conflict is a field that was changed differently on both sides of a merge.
+// The merged recipe keeps `Ours`; `Theirs` is reported so nothing gets lost.
+type Conflict struct {
+	Field  string
+	Ours   []string
+	Theirs []string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf(
+		"%s: kept '%s', dropped '%s'",
+		c.Field,
+		strings.Join(c.Ours, ", "),
+		strings.Join(c.Theirs, ", "),
+	)
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setField replaces the values of the field called `name`.
+func (r *Recipe) setField(name string, values []string) error {
+	value := ""
+	if len(values) > 0 {
+		value = values[0]
+	}
+
+	switch name {
+	case "name":
+		r.Data.Name = value
+	case "category":
+		r.Data.Category = value
+	case "persons":
+		persons, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("persons must be a positive number, not '%s'", value)
+		}
+		r.Data.Persons = uint(persons)
+	case "duration.preparation":
+		r.Data.Duration.Preparation = value
+	case "duration.cooking":
+		r.Data.Duration.Cooking = value
+	case "duration.total":
+		r.Data.Duration.Total = value
+	case "images":
+		r.Data.Images = values
+	case "cover":
+		r.Data.Cover = value
+	case "captions":
+		return r.setCaptionList(values)
+	case "ingredients":
+		r.Data.Ingredients = values
+	case "spices":
+		r.Data.Spices = values
+	case "complementaries":
+		r.Data.Complementaries = values
+	case "recipe":
+		r.Data.Recipe = values
+	case "notes":
+		r.Data.Notes = values
+	default:
+		return fmt.Errorf("Unknown field '%s'", name)
+	}
+
+	return nil
+}
+
+// matchLists maps every index of `base` to the index of the same entry in
+// `other`, or -1 if it is not part of their longest common subsequence.
+func matchLists(base, other []string) []int {
+	matches := make([]int, len(base))
+	i, j := 0, 0
+	for _, line := range DiffLists(base, other) {
+		switch line.Op {
+		case ' ':
+			matches[i] = j
+			i++
+			j++
+		case '-':
+			matches[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+
+	return matches
+}
+
+// mergeChunk resolves one region between two stable entries.
+func mergeChunk(base, ours, theirs []string) ([]string, bool) {
+	switch {
+	case equalValues(ours, theirs), equalValues(base, theirs):
+		return ours, true
+	case equalValues(base, ours):
+		return theirs, true
+	case len(base) == 0:
+		// Both sides inserted something at the same place: keep both.
+		return MergeSet(nil, ours, theirs), true
+	default:
+		return ours, false
+	}
+}
+
+// MergeLists does a three-way merge of a list entry by entry, like diff3.
+// Regions changed on both sides in different ways are returned as conflicts
+// and keep the entries of `ours`.
+func MergeLists(field string, base, ours, theirs []string) ([]string, []Conflict) {
+	ourMatches, theirMatches := matchLists(base, ours), matchLists(base, theirs)
+
+	merged := []string{}
+	conflicts := []Conflict{}
+
+	i, j, k := 0, 0, 0
+	for stable := 0; stable <= len(base); stable++ {
+		ourEnd, theirEnd := len(ours), len(theirs)
+		if stable < len(base) {
+			if ourMatches[stable] < 0 || theirMatches[stable] < 0 {
+				continue
+			}
+
+			ourEnd, theirEnd = ourMatches[stable], theirMatches[stable]
+		}
+
+		chunk, ok := mergeChunk(base[i:stable], ours[j:ourEnd], theirs[k:theirEnd])
+		if !ok {
+			conflicts = append(conflicts, Conflict{
+				Field:  field,
+				Ours:   ours[j:ourEnd],
+				Theirs: theirs[k:theirEnd],
+			})
+		}
+
+		merged = append(merged, chunk...)
+		if stable < len(base) {
+			merged = append(merged, base[stable])
+		}
+
+		i, j, k = stable+1, ourEnd+1, theirEnd+1
+	}
+
+	return merged, conflicts
+}
+
+// Merge does a three-way merge of a recipe field by field.
+// Fields changed only on one side are taken from that side, lists are merged
+// entry by entry. Fields changed on both sides in different ways are
+// returned as conflicts and keep the value of `ours`.
+func Merge(base, ours, theirs *Recipe) (*Recipe, []Conflict) {
+	merged := NewRecipe(ours.Name)
+	conflicts := []Conflict{}
+
+	baseFields, theirFields := base.Fields(), theirs.Fields()
+	for idx, ourField := range ours.Fields() {
+		baseValues, ourValues, theirValues := baseFields[idx].Values, ourField.Values, theirFields[idx].Values
+
+		if ourField.List {
+			values, listConflicts := MergeLists(ourField.Name, baseValues, ourValues, theirValues)
+			conflicts = append(conflicts, listConflicts...)
+			merged.setField(ourField.Name, values)
+			continue
+		}
+
+		values, ok := mergeChunk(baseValues, ourValues, theirValues)
+		if !ok {
+			conflicts = append(conflicts, Conflict{
+				Field:  ourField.Name,
+				Ours:   ourValues,
+				Theirs: theirValues,
+			})
+		}
+
+		// Field names come from Fields(), so this cannot fail.
+		merged.setField(ourField.Name, values)
+	}
+
+	return &merged, conflicts
+}
+
+// MergeSet merges three versions of a set of names. A name is kept if it is
+// on both sides, or if it was added on one side. Removals on either side win.
+func MergeSet(base, ours, theirs []string) []string {
+	contains := func(names []string, name string) bool {
+		for _, other := range names {
+			if other == name {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	merged := []string{}
+	for _, name := range ours {
+		if contains(theirs, name) || !contains(base, name) {
+			merged = append(merged, name)
+		}
+	}
+
+	for _, name := range theirs {
+		if !contains(ours, name) && !contains(base, name) {
+			merged = append(merged, name)
+		}
+	}
+
+	return merged
+}
+
+// MergeIndex merges three versions of the index into `i`, see MergeSet.
+// Whether a recipe is archived is taken from the side that changed it.
+func (i *Index) MergeIndex(base, ours, theirs map[string]bool) {
+	keys := func(recipes map[string]bool) []string {
+		names := []string{}
+		for name := range recipes {
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	i.Recipes = make(map[string]bool)
+	for _, name := range MergeSet(keys(base), keys(ours), keys(theirs)) {
+		active, ok := ours[name]
+		if other, inTheirs := theirs[name]; !ok || (inTheirs && active == base[name] && other != base[name]) {
+			active = other
+		}
+
+		i.Recipes[name] = active
+	}
+}
+
+// MergeCollections merges three versions of all collections into `c`.
+// Collections and their members are merged like names in MergeSet.
+func (c *Collections) MergeCollections(base, ours, theirs map[string][]string) {
+	keys := func(sets map[string][]string) []string {
+		names := []string{}
+		for name := range sets {
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	c.Sets = make(map[string][]string)
+	for _, name := range MergeSet(keys(base), keys(ours), keys(theirs)) {
+		c.Sets[name] = MergeSet(base[name], ours[name], theirs[name])
+	}
+}
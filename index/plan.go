@@ -0,0 +1,564 @@
+package index
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of plan rules, see PlanRule.
+const (
+	// PlanRuleMaxPreparation limits the preparation time to Value.
+	PlanRuleMaxPreparation = "max-preparation"
+	// PlanRuleRequire only allows matching recipes.
+	PlanRuleRequire = "require"
+	// PlanRuleExclude only allows recipes that do not match.
+	PlanRuleExclude = "exclude"
+	// PlanRuleMaxPerWeek allows at most Value matching recipes per week.
+	PlanRuleMaxPerWeek = "max-per-week"
+	// PlanRuleNoRepeatCategory forbids the same category on consecutive days.
+	PlanRuleNoRepeatCategory = "no-repeat-category"
+	// PlanRuleMinGap wants at least Value days between the same recipe,
+	// counting from when it was cooked the last time.
+	PlanRuleMinGap = "min-gap"
+)
+
+const (
+	// hardCost and softCost are what breaking a rule costs a plan.
+	hardCost = 1000
+	softCost = 10
+
+	// planAttempts and planBudget bound the search for a plan
+	// that keeps all hard rules.
+	planAttempts = 10
+	planBudget   = 20000
+
+	// planPasses bounds how often a plan is improved recipe by recipe.
+	planPasses = 3
+)
+
+// PlanConfig is the `plan` section of the repository config.
+type PlanConfig struct {
+	Rules []PlanRule `yaml:"rules,omitempty"`
+}
+
+// PlanRule restricts which recipes the planner puts on which day.
+type PlanRule struct {
+	// Rule is the kind of the rule, like "max-preparation".
+	Rule string `yaml:"rule"`
+	// Days limits the rule to these weekdays ("mon", "weekdays", ...).
+	Days []string `yaml:"days,omitempty"`
+	// Category and Ingredient select the recipes the rule is about.
+	Category   string `yaml:"category,omitempty"`
+	Ingredient string `yaml:"ingredient,omitempty"`
+	// Value is the limit of the rule: a duration or a number.
+	Value string `yaml:"value,omitempty"`
+	// Soft rules are kept if possible; hard rules are only broken if no
+	// plan keeps all of them.
+	Soft bool `yaml:"soft,omitempty"`
+}
+
+func (r *PlanRule) String() string {
+	parts := []string{r.Rule}
+	if r.Category != "" {
+		parts = append(parts, "category "+r.Category)
+	}
+
+	if r.Ingredient != "" {
+		parts = append(parts, "ingredient "+r.Ingredient)
+	}
+
+	if r.Value != "" {
+		parts = append(parts, r.Value)
+	}
+
+	if len(r.Days) > 0 {
+		parts = append(parts, "on "+strings.Join(r.Days, ", "))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+var weekdayNames = map[string][]time.Weekday{
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend":  {time.Saturday, time.Sunday},
+}
+
+func init() {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		weekdayNames[name] = []time.Weekday{day}
+		weekdayNames[name[:3]] = []time.Weekday{day}
+	}
+}
+
+// planRule is a PlanRule with its values parsed.
+type planRule struct {
+	*PlanRule
+	days     map[time.Weekday]bool
+	duration time.Duration
+	number   int
+
+	// matched tells for each recipe of the planner if the rule is about it.
+	matched []bool
+}
+
+func compilePlanRule(rule *PlanRule) (*planRule, error) {
+	compiled := &planRule{PlanRule: rule}
+	if len(rule.Days) > 0 {
+		compiled.days = make(map[time.Weekday]bool)
+	}
+
+	for _, name := range rule.Days {
+		days, ok := weekdayNames[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("Plan rule '%s': unknown day '%s'", rule, name)
+		}
+
+		for _, day := range days {
+			compiled.days[day] = true
+		}
+	}
+
+	selects := rule.Category != "" || rule.Ingredient != ""
+	var err error
+
+	switch rule.Rule {
+	case PlanRuleMaxPreparation:
+		compiled.duration, err = ParseDuration(rule.Value)
+	case PlanRuleRequire, PlanRuleExclude:
+		if !selects {
+			err = fmt.Errorf("needs a category or an ingredient")
+		}
+	case PlanRuleMaxPerWeek:
+		compiled.number, err = strconv.Atoi(rule.Value)
+		if err == nil && !selects {
+			err = fmt.Errorf("needs a category or an ingredient")
+		}
+	case PlanRuleMinGap:
+		compiled.number, err = strconv.Atoi(rule.Value)
+	case PlanRuleNoRepeatCategory:
+	default:
+		err = fmt.Errorf("unknown rule, use one of: %s", strings.Join([]string{
+			PlanRuleMaxPreparation,
+			PlanRuleRequire,
+			PlanRuleExclude,
+			PlanRuleMaxPerWeek,
+			PlanRuleNoRepeatCategory,
+			PlanRuleMinGap,
+		}, ", "))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Plan rule '%s': %v", rule, err)
+	}
+
+	return compiled, nil
+}
+
+func (r *planRule) appliesOn(date time.Time) bool {
+	return r.days == nil || r.days[date.Weekday()]
+}
+
+func (r *planRule) matches(recipe *Recipe) bool {
+	if r.Category != "" && !strings.EqualFold(r.Category, recipe.Data.Category) {
+		return false
+	}
+
+	if r.Ingredient != "" {
+		for _, ingredient := range recipe.Data.Ingredients {
+			if strings.Contains(strings.ToLower(ingredient), strings.ToLower(r.Ingredient)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// PlanViolation tells that the plan breaks `Rule` on `Date`.
+type PlanViolation struct {
+	Date   time.Time
+	Recipe string
+	Rule   *PlanRule
+}
+
+// Plan is a recipe for each day.
+type Plan struct {
+	Dates      []time.Time
+	Recipes    []string
+	Violations []PlanViolation
+}
+
+// Planner finds plans that keep the rules of the config.
+type Planner struct {
+	recipes []*Recipe
+	rules   []*planRule
+
+	// preparations are the preparation times of the recipes, 0 if unknown.
+	preparations []time.Duration
+
+	// lastCooked are the dates the recipes were cooked the last time,
+	// zero if never.
+	lastCooked []time.Time
+
+	random *rand.Rand
+
+	// window is how many days a choice influences the cost of later days.
+	window int
+}
+
+// NewPlanner plans with the recipes of `idx`, the plan rules of `config`
+// and the dates in `cooked`. The recipes are loaded from the store.
+func NewPlanner(idx *Index, config *Config, cooked *Cooked, seed int64) (*Planner, error) {
+	names := []string{}
+	for name := range idx.Recipes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	planner := &Planner{random: rand.New(rand.NewSource(seed))}
+	for _, name := range names {
+		recipe := NewRecipe(name)
+		if err := recipe.Load(idx.Store()); err != nil {
+			return nil, err
+		}
+
+		preparation, err := ParseDuration(recipe.Data.Duration.Preparation)
+		if err != nil {
+			preparation = 0
+		}
+
+		last, _ := cooked.Last(name)
+		planner.recipes = append(planner.recipes, &recipe)
+		planner.preparations = append(planner.preparations, preparation)
+		planner.lastCooked = append(planner.lastCooked, last)
+	}
+
+	for idx := range config.Plan.Rules {
+		rule, err := compilePlanRule(&config.Plan.Rules[idx])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, recipe := range planner.recipes {
+			rule.matched = append(rule.matched, rule.matches(recipe))
+		}
+
+		planner.rules = append(planner.rules, rule)
+		if rule.Rule == PlanRuleMinGap && rule.number > planner.window {
+			planner.window = rule.number
+		}
+	}
+
+	// At least a week for max-per-week and variety.
+	if planner.window < 7 {
+		planner.window = 7
+	}
+
+	return planner, nil
+}
+
+// lookback is the first day before `day` that rules look at.
+func (p *Planner) lookback(day int) int {
+	if day < p.window {
+		return 0
+	}
+
+	return day - p.window
+}
+
+// broken returns the rules broken by putting recipe `choice` on day
+// `day`, given the choices for the days before.
+func (p *Planner) broken(dates []time.Time, choices []int, day, choice int) []*planRule {
+	recipe, date := p.recipes[choice], dates[day]
+	broken := []*planRule{}
+	for _, rule := range p.rules {
+		if !rule.appliesOn(date) {
+			continue
+		}
+
+		ok := true
+		switch rule.Rule {
+		case PlanRuleMaxPreparation:
+			ok = p.preparations[choice] <= rule.duration
+		case PlanRuleRequire:
+			ok = rule.matched[choice]
+		case PlanRuleExclude:
+			ok = !rule.matched[choice]
+		case PlanRuleMaxPerWeek:
+			if !rule.matched[choice] {
+				break
+			}
+
+			year, week := date.ISOWeek()
+			count := 1
+			for before := p.lookback(day); before < day; before++ {
+				otherYear, otherWeek := dates[before].ISOWeek()
+				if otherYear == year && otherWeek == week && rule.appliesOn(dates[before]) && rule.matched[choices[before]] {
+					count++
+				}
+			}
+
+			ok = count <= rule.number
+		case PlanRuleNoRepeatCategory:
+			if day > 0 && recipe.Data.Category != "" {
+				ok = !strings.EqualFold(recipe.Data.Category, p.recipes[choices[day-1]].Data.Category)
+			}
+		case PlanRuleMinGap:
+			last := p.lastCooked[choice]
+			cooked := !last.IsZero() && last.Before(date)
+			for before := p.lookback(day); before < day; before++ {
+				if choices[before] == choice {
+					last, cooked = dates[before], true
+				}
+			}
+
+			ok = !cooked || date.Sub(last) >= time.Duration(rule.number)*24*time.Hour
+		}
+
+		if !ok {
+			broken = append(broken, rule)
+		}
+	}
+
+	return broken
+}
+
+// cost of putting recipe `choice` on day `day`. Recipes not used within
+// the window are preferred, so that the plan has some variety.
+func (p *Planner) cost(dates []time.Time, choices []int, day, choice int) (hard, soft int) {
+	for _, rule := range p.broken(dates, choices, day, choice) {
+		if rule.Soft {
+			soft += softCost
+		} else {
+			hard += hardCost
+		}
+	}
+
+	for before := p.lookback(day); before < day; before++ {
+		if choices[before] == choice {
+			soft++
+		}
+	}
+
+	return hard, soft
+}
+
+// planCandidate is a recipe that could go on a certain day.
+type planCandidate struct {
+	choice     int
+	hard, soft int
+}
+
+// candidates returns all recipes for day `day` ordered by their cost;
+// recipes that cost the same are shuffled.
+func (p *Planner) candidates(dates []time.Time, choices []int, day int) []planCandidate {
+	candidates := []planCandidate{}
+	for _, choice := range p.random.Perm(len(p.recipes)) {
+		hard, soft := p.cost(dates, choices, day, choice)
+		candidates = append(candidates, planCandidate{choice, hard, soft})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		return a.hard < b.hard || (a.hard == b.hard && a.soft < b.soft)
+	})
+
+	return candidates
+}
+
+// unavoidable returns for each day what breaking hard rules costs at least,
+// because no recipe keeps them on that day (like requiring a category no
+// recipe is in). Only rules that do not depend on other days are counted.
+func (p *Planner) unavoidable(dates []time.Time) []int {
+	costs := []int{}
+	for _, date := range dates {
+		least := -1
+		for choice := range p.recipes {
+			cost := 0
+			for _, rule := range p.rules {
+				if rule.Soft || !rule.appliesOn(date) {
+					continue
+				}
+
+				switch rule.Rule {
+				case PlanRuleMaxPreparation:
+					if p.preparations[choice] > rule.duration {
+						cost += hardCost
+					}
+				case PlanRuleRequire:
+					if !rule.matched[choice] {
+						cost += hardCost
+					}
+				case PlanRuleExclude:
+					if rule.matched[choice] {
+						cost += hardCost
+					}
+				}
+			}
+
+			if least < 0 || cost < least {
+				least = cost
+			}
+		}
+
+		costs = append(costs, least)
+	}
+
+	return costs
+}
+
+// search fills the days from `day` on with recipes that keep all hard
+// rules that can be kept (see unavoidable), trying the cheapest recipes
+// first. It gives up once `budget` recipes were tried.
+func (p *Planner) search(dates []time.Time, choices, allowed []int, day int, budget *int) bool {
+	if day == len(dates) {
+		return true
+	}
+
+	for _, candidate := range p.candidates(dates, choices, day) {
+		if candidate.hard > allowed[day] || *budget <= 0 {
+			break
+		}
+
+		*budget--
+		choices[day] = candidate.choice
+		if p.search(dates, choices, allowed, day+1, budget) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// greedy fills the days with the cheapest recipe each, breaking hard
+// rules where there is no other way.
+func (p *Planner) greedy(dates []time.Time, choices []int) {
+	for day := range dates {
+		choices[day] = p.candidates(dates, choices, day)[0].choice
+	}
+}
+
+// cost of the plan from day `from` up to (not including) `to`.
+func (p *Planner) costBetween(dates []time.Time, choices []int, from, to int) int {
+	if to > len(dates) {
+		to = len(dates)
+	}
+
+	total := 0
+	for day := from; day < to; day++ {
+		hard, soft := p.cost(dates, choices, day, choices[day])
+		total += hard + soft
+	}
+
+	return total
+}
+
+// improve swaps single recipes of the plan as long as that makes it
+// cheaper, at most `planPasses` times over all days. A swap only changes
+// the cost of its day and of the days within the window after it.
+func (p *Planner) improve(dates []time.Time, choices []int) {
+	for pass, improved := 0, true; improved && pass < planPasses; pass++ {
+		improved = false
+		for day := range dates {
+			best, bestCost := choices[day], p.costBetween(dates, choices, day, day+p.window+1)
+			for choice := range p.recipes {
+				choices[day] = choice
+				if cost := p.costBetween(dates, choices, day, day+p.window+1); cost < bestCost {
+					best, bestCost, improved = choice, cost, true
+				}
+			}
+
+			choices[day] = best
+		}
+	}
+}
+
+// Plan picks a recipe for each of `dates`. It tries to keep all hard rules
+// and as many soft rules as possible; the rules it broke are listed in
+// the returned plan.
+func (p *Planner) Plan(dates []time.Time) (*Plan, error) {
+	if len(p.recipes) == 0 {
+		return nil, fmt.Errorf("No recipes found")
+	}
+
+	var best []int
+	bestCost := -1
+	allowed := p.unavoidable(dates)
+	for attempt := 0; attempt < planAttempts; attempt++ {
+		choices, budget := make([]int, len(dates)), planBudget
+		if !p.search(dates, choices, allowed, 0, &budget) {
+			continue
+		}
+
+		p.improve(dates, choices)
+		if cost := p.costBetween(dates, choices, 0, len(dates)); best == nil || cost < bestCost {
+			best, bestCost = choices, cost
+		}
+	}
+
+	if best == nil {
+		for attempt := 0; attempt < planAttempts; attempt++ {
+			choices := make([]int, len(dates))
+			p.greedy(dates, choices)
+			p.improve(dates, choices)
+			if cost := p.costBetween(dates, choices, 0, len(dates)); best == nil || cost < bestCost {
+				best, bestCost = choices, cost
+			}
+		}
+	}
+
+	plan := &Plan{Dates: dates}
+	for day, choice := range best {
+		plan.Recipes = append(plan.Recipes, p.recipes[choice].Name)
+		for _, rule := range p.broken(dates, best, day, choice) {
+			plan.Violations = append(plan.Violations, PlanViolation{
+				Date:   dates[day],
+				Recipe: p.recipes[choice].Name,
+				Rule:   rule.PlanRule,
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// Explain says why `rule` could not be kept, as far as the planner can tell.
+func (p *Planner) Explain(rule *PlanRule) string {
+	for _, compiled := range p.rules {
+		if compiled.PlanRule != rule {
+			continue
+		}
+
+		matching := 0
+		for _, matched := range compiled.matched {
+			if matched {
+				matching++
+			}
+		}
+
+		switch rule.Rule {
+		case PlanRuleRequire:
+			if matching == 0 {
+				return "no recipe matches"
+			}
+		case PlanRuleExclude:
+			if matching == len(p.recipes) {
+				return "every recipe matches"
+			}
+		case PlanRuleMinGap:
+			if len(p.recipes) < compiled.number {
+				return fmt.Sprintf("only %d recipes to choose from", len(p.recipes))
+			}
+		}
+	}
+
+	return "it conflicts with other rules"
+}
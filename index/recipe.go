@@ -5,10 +5,12 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"math"
-	"path/filepath"
+	"path"
 	"sort"
 	"strconv"
 	"unicode"
+
+	"github.com/serztle/nom/storage"
 )
 
 // TODO: type Metric
@@ -20,12 +22,13 @@ type Range struct {
 
 type Recipe struct {
 	Name string
-	Dir  string
 	Data struct {
 		Name     string
 		Category string
 		Persons  uint
 		Images   []string
+		Cover    string            `yaml:",omitempty"`
+		Captions map[string]string `yaml:",omitempty"`
 		Duration struct {
 			Preparation string
 			Cooking     string
@@ -35,29 +38,44 @@ type Recipe struct {
 		Spices          []string
 		Complementaries []string
 		Recipe          []string
+		Notes           []string `yaml:",omitempty"`
 	}
 }
 
-func NewRecipe(dir string, name string) Recipe {
-	return Recipe{Name: name, Dir: dir}
+func NewRecipe(name string) Recipe {
+	return Recipe{Name: name}
 }
 
-func (r *Recipe) Path() string {
-	return filepath.Join(r.Dir, r.Name)
+// ImageDir is the directory in the store where images of the recipe went
+// before they were stored by content (see ImagePath).
+func (r *Recipe) ImageDir() string {
+	return path.Join(ImagesDir, r.Name)
 }
 
-func (r *Recipe) Load() error {
-	return r.Parse(r.Path())
+// Load reads the recipe called `r.Name` from `store`.
+func (r *Recipe) Load(store storage.Store) error {
+	content, err := store.Read(r.Name)
+	if err != nil {
+		return err
+	}
+
+	return r.Unmarshal(content, r.Name)
 }
 
+// Parse reads a recipe from a file outside of any store.
 func (r *Recipe) Parse(path string) error {
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
+	return r.Unmarshal(content, path)
+}
+
+// Unmarshal fills the recipe data from yaml `content` read from `origin`.
+func (r *Recipe) Unmarshal(content []byte, origin string) error {
 	if err := yaml.Unmarshal(content, &r.Data); err != nil {
-		return fmt.Errorf("Possibly not valid yaml in '%s' (%v)", path, err)
+		return fmt.Errorf("Possibly not valid yaml in '%s' (%v)", origin, err)
 	}
 
 	return nil
@@ -161,17 +179,14 @@ func (r *Recipe) ImageExists(name string) bool {
 	return false
 }
 
-func (r *Recipe) Save(path string) error {
+// Save writes the recipe as `r.Name` into `store`.
+func (r *Recipe) Save(store storage.Store) error {
 	content, err := r.String()
 	if err != nil {
 		return err
 	}
 
-	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
-		return err
-	}
-
-	return nil
+	return store.Write(r.Name, []byte(content))
 }
 
 func (r *Recipe) String() (string, error) {
@@ -0,0 +1,230 @@
+package index
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DuplicateThreshold is the similarity from which two recipes are
+// considered likely duplicates.
+const DuplicateThreshold = 0.75
+
+var parenthesized = regexp.MustCompile(`\([^)]*\)`)
+
+// Duplicate is a pair of similar recipes.
+type Duplicate struct {
+	A, B  string
+	Score float64
+}
+
+// normalizeName lowercases `name`, spells out umlauts and drops
+// everything but letters and digits.
+func normalizeName(name string) string {
+	replacer := strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+	name = replacer.Replace(strings.ToLower(name))
+
+	return strings.Map(func(char rune) rune {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
+			return char
+		}
+		return -1
+	}, name)
+}
+
+// nameSimilarity is the Dice coefficient of the character bigrams of two names.
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == b {
+		return 1
+	}
+
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	bigrams := make(map[string]int)
+	for idx := 0; idx < len(a)-1; idx++ {
+		bigrams[a[idx:idx+2]]++
+	}
+
+	common := 0
+	for idx := 0; idx < len(b)-1; idx++ {
+		if bigrams[b[idx:idx+2]] > 0 {
+			bigrams[b[idx:idx+2]]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)-1+len(b)-1)
+}
+
+// ingredientKey reduces an ingredient like "2 Zehen Knoblauch, gehackt"
+// to what it is, without amount, unit and remarks: "zehen knoblauch".
+func ingredientKey(ingredient string) string {
+	ingredient = parenthesized.ReplaceAllString(ingredient, "")
+	ingredient = strings.SplitN(ingredient, ",", 2)[0]
+
+	words := []string{}
+	for _, word := range strings.Fields(strings.ToLower(ingredient)) {
+		if len(words) == 0 && strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			continue
+		}
+
+		words = append(words, word)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// ingredientSimilarity is the Jaccard index of the ingredients of two recipes.
+func ingredientSimilarity(a, b *Recipe) (float64, bool) {
+	keys := func(r *Recipe) map[string]bool {
+		set := make(map[string]bool)
+		for _, ingredient := range r.Data.Ingredients {
+			if key := ingredientKey(ingredient); key != "" {
+				set[key] = true
+			}
+		}
+		return set
+	}
+
+	setA, setB := keys(a), keys(b)
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0, false
+	}
+
+	common := 0
+	for key := range setA {
+		if setB[key] {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(setA)+len(setB)-common), true
+}
+
+// Similarity rates from 0 to 1 how much two recipes look like the same dish,
+// by their names and the overlap of their ingredients.
+func Similarity(a, b *Recipe) float64 {
+	name := nameSimilarity(a.Name, b.Name)
+	if display := nameSimilarity(a.Data.Name, b.Data.Name); display > name {
+		name = display
+	}
+
+	ingredients, ok := ingredientSimilarity(a, b)
+	if !ok {
+		return name
+	}
+
+	return (name + ingredients) / 2
+}
+
+// SimilarRecipes returns the recipes of the index that are at least
+// `threshold` similar to `recipe`, most similar first.
+func (i *Index) SimilarRecipes(recipe *Recipe, threshold float64) ([]Duplicate, error) {
+	similar := []Duplicate{}
+	for name := range i.Recipes {
+		if name == recipe.Name {
+			continue
+		}
+
+		other := NewRecipe(name)
+		if err := other.Load(i.store); err != nil {
+			return nil, err
+		}
+
+		if score := Similarity(recipe, &other); score >= threshold {
+			similar = append(similar, Duplicate{A: recipe.Name, B: name, Score: score})
+		}
+	}
+
+	sortDuplicates(similar)
+	return similar, nil
+}
+
+// Duplicates returns all pairs of recipes in the index that are at least
+// `threshold` similar, most similar first.
+func (i *Index) Duplicates(threshold float64) ([]Duplicate, error) {
+	names := []string{}
+	for name := range i.Recipes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	recipes := []Recipe{}
+	for _, name := range names {
+		recipe := NewRecipe(name)
+		if err := recipe.Load(i.store); err != nil {
+			return nil, err
+		}
+
+		recipes = append(recipes, recipe)
+	}
+
+	duplicates := []Duplicate{}
+	for a := range recipes {
+		for b := a + 1; b < len(recipes); b++ {
+			if score := Similarity(&recipes[a], &recipes[b]); score >= threshold {
+				duplicates = append(duplicates, Duplicate{A: recipes[a].Name, B: recipes[b].Name, Score: score})
+			}
+		}
+	}
+
+	sortDuplicates(duplicates)
+	return duplicates, nil
+}
+
+func sortDuplicates(duplicates []Duplicate) {
+	sort.SliceStable(duplicates, func(a, b int) bool {
+		if duplicates[a].Score != duplicates[b].Score {
+			return duplicates[a].Score > duplicates[b].Score
+		}
+
+		return duplicates[a].A+"\x00"+duplicates[a].B < duplicates[b].A+"\x00"+duplicates[b].B
+	})
+}
+
+// Absorb takes everything over from `other` that the recipe lacks: empty
+// fields are filled, list entries and images it doesn't have are appended.
+func (r *Recipe) Absorb(other *Recipe) error {
+	otherFields := make(map[string]Field)
+	for _, field := range other.Fields() {
+		otherFields[field.Name] = field
+	}
+
+	for _, field := range r.Fields() {
+		theirs := otherFields[field.Name]
+		values := field.Values
+		if !field.List {
+			if values[0] == "" || values[0] == "0" {
+				values = theirs.Values
+			}
+		} else {
+			values = append([]string{}, values...)
+			for _, value := range theirs.Values {
+				if !containsValue(values, value) {
+					values = append(values, value)
+				}
+			}
+		}
+
+		if err := r.setField(field.Name, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsValue(values []string, value string) bool {
+	for _, other := range values {
+		if other == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package index
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/serztle/nom/storage"
+)
+
+// TemplatesDir holds recipe skeletons per category, used when adding recipes.
+const TemplatesDir = ".templates"
+
+// TemplatePath is where the template for `category` is stored.
+func TemplatePath(category string) string {
+	return path.Join(TemplatesDir, category)
+}
+
+// CheckTemplateCategory makes sure the template for `category` stays
+// inside TemplatesDir. Categories may have parts like "pasta/italienisch",
+// but no part may be empty or start with a dot.
+func CheckTemplateCategory(category string) error {
+	for _, part := range strings.Split(category, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return fmt.Errorf("'%s' can't be used as category of a template", category)
+		}
+	}
+
+	return nil
+}
+
+// LoadTemplate reads the template for `category`. The second return value
+// is false if there is none.
+func LoadTemplate(store storage.Store, category string) (*Recipe, bool, error) {
+	if category == "" {
+		return nil, false, nil
+	}
+
+	if err := CheckTemplateCategory(category); err != nil {
+		return nil, false, err
+	}
+
+	if !store.Exists(TemplatePath(category)) {
+		return nil, false, nil
+	}
+
+	content, err := store.Read(TemplatePath(category))
+	if err != nil {
+		return nil, false, err
+	}
+
+	template := &Recipe{}
+	if err := template.Unmarshal(content, TemplatePath(category)); err != nil {
+		return nil, false, err
+	}
+
+	template.Data.Category = category
+	return template, true, nil
+}
+
+// TemplateNames returns the categories that have a template, sorted.
+func TemplateNames(store storage.Store) ([]string, error) {
+	paths, err := store.List(TemplatesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, path := range paths {
+		names = append(names, strings.TrimPrefix(path, TemplatesDir+"/"))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,71 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ParseDuration parses recipe durations like "45m", "2h" or "1h 30m".
+func ParseDuration(value string) (time.Duration, error) {
+	return time.ParseDuration(strings.Replace(value, " ", "", -1))
+}
+
+// UnmarshalStrict is like Unmarshal, but also fails on unknown fields
+// and on values that don't make sense (see Validate).
+func (r *Recipe) UnmarshalStrict(content []byte, origin string) error {
+	if err := yaml.UnmarshalStrict(content, &r.Data); err != nil {
+		return fmt.Errorf("Not a valid recipe in '%s' (%v)", origin, err)
+	}
+
+	return r.Validate()
+}
+
+// Validate checks the values of the recipe.
+func (r *Recipe) Validate() error {
+	for _, field := range r.Fields() {
+		if err := r.validateField(field.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateField checks the field called `name`, and the fields that
+// depend on it.
+func (r *Recipe) validateField(name string) error {
+	durations := map[string]string{
+		"duration.preparation": r.Data.Duration.Preparation,
+		"duration.cooking":     r.Data.Duration.Cooking,
+		"duration.total":       r.Data.Duration.Total,
+	}
+
+	switch name {
+	case "name":
+		if strings.TrimSpace(r.Data.Name) == "" {
+			return fmt.Errorf("The recipe needs a name")
+		}
+	case "duration.preparation", "duration.cooking", "duration.total":
+		if value := durations[name]; value != "" {
+			if _, err := ParseDuration(value); err != nil {
+				field := strings.TrimPrefix(name, "duration.")
+				return fmt.Errorf("Duration %s '%s' should look like 45m, 2h or 1h30m", field, value)
+			}
+		}
+	case "images", "cover":
+		for _, image := range r.Data.Images {
+			if strings.TrimSpace(image) == "" {
+				return fmt.Errorf("Images may not be empty")
+			}
+		}
+
+		if r.Data.Cover != "" && !containsValue(r.Data.Images, r.Data.Cover) {
+			return fmt.Errorf("The cover '%s' is not one of the images", r.Data.Cover)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/serztle/nom/util"
+)
+
+// FS is a Store backed by a directory that is also a git repository.
+// Every Commit stages exactly the paths that were touched before.
+type FS struct {
+	dir     string
+	git     util.Git
+	tx      *util.Transaction
+	touched map[string]bool
+}
+
+func NewFS(dir string) *FS {
+	return &FS{
+		dir:     dir,
+		git:     util.NewGit(dir),
+		touched: make(map[string]bool),
+	}
+}
+
+// Dir returns the directory on disk the store operates on.
+func (fs *FS) Dir() string {
+	return fs.dir
+}
+
+// SetAuthor sets the author used for all following commits.
+func (fs *FS) SetAuthor(author string) {
+	fs.git.Author = author
+}
+
+// Git gives access to the underlying repository.
+func (fs *FS) Git() *util.Git {
+	return &fs.git
+}
+
+func (fs *FS) abs(path string) string {
+	return filepath.Join(fs.dir, filepath.FromSlash(path))
+}
+
+func (fs *FS) List(dir string) ([]string, error) {
+	root := fs.abs(dir)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(fs.dir, path)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, filepath.ToSlash(relPath))
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Listing '%s' failed (%v)", dir, err)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (fs *FS) Exists(path string) bool {
+	_, err := os.Stat(fs.abs(path))
+	return err == nil
+}
+
+func (fs *FS) Read(path string) ([]byte, error) {
+	return ioutil.ReadFile(fs.abs(path))
+}
+
+// touch remembers `path` for a running transaction and the next commit.
+func (fs *FS) touch(path string) error {
+	if fs.tx != nil {
+		if err := fs.tx.Touch(filepath.FromSlash(path)); err != nil {
+			return err
+		}
+	}
+
+	fs.touched[path] = true
+	return nil
+}
+
+func (fs *FS) Write(path string, data []byte) error {
+	if err := fs.touch(path); err != nil {
+		return err
+	}
+
+	absPath := fs.abs(path)
+	if err := os.MkdirAll(filepath.Dir(absPath), util.DirPerm); err != nil {
+		return err
+	}
+
+	return util.WriteFile(absPath, data)
+}
+
+func (fs *FS) Delete(path string) error {
+	if err := fs.touch(path); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(fs.abs(path))
+}
+
+func (fs *FS) Rename(oldPath, newPath string) error {
+	if err := fs.touch(oldPath); err != nil {
+		return err
+	}
+
+	if err := fs.touch(newPath); err != nil {
+		return err
+	}
+
+	absNewPath := fs.abs(newPath)
+	if err := os.MkdirAll(filepath.Dir(absNewPath), util.DirPerm); err != nil {
+		return err
+	}
+
+	return os.Rename(fs.abs(oldPath), absNewPath)
+}
+
+func (fs *FS) Commit(message string) error {
+	var paths []string
+	for path := range fs.touched {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if fs.Exists(path) {
+			if err := fs.git.Add(path); err != nil {
+				return err
+			}
+		} else if err := fs.git.Exec("rm", "-r", "-q", "--cached", "--ignore-unmatch", path); err != nil {
+			return err
+		}
+	}
+
+	fs.touched = make(map[string]bool)
+	if !fs.git.HasChanges(true) {
+		return ErrNoChanges
+	}
+
+	return fs.git.Commit(message)
+}
+
+func (fs *FS) WithTransaction(fn func() error) error {
+	if fs.tx != nil {
+		// Already part of a transaction; the outermost one decides.
+		return fn()
+	}
+
+	return fs.git.WithTransaction(func(tx *util.Transaction) error {
+		fs.tx = tx
+		defer func() {
+			fs.tx = nil
+		}()
+
+		if err := fn(); err != nil {
+			fs.touched = make(map[string]bool)
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (fs *FS) Log(path string) ([]util.LogEntry, error) {
+	return fs.git.Log(path)
+}
+
+func (fs *FS) ReadAt(rev, path string) ([]byte, error) {
+	return fs.git.Show(rev, path)
+}
+
+func (fs *FS) Modified() (map[string]time.Time, error) {
+	return fs.git.Modified()
+}
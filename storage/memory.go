@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serztle/nom/util"
+)
+
+// Commit is a snapshot of a Memory store taken by Memory.Commit.
+type Commit struct {
+	Message string
+	Date    time.Time
+	Files   map[string][]byte
+}
+
+// Memory is a Store that keeps everything in memory.
+// It is useful for embedding nom and for tests.
+type Memory struct {
+	files   map[string][]byte
+	Commits []Commit
+}
+
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string][]byte)}
+}
+
+func isBelow(path, dir string) bool {
+	return dir == "" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+func (m *Memory) List(dir string) ([]string, error) {
+	var paths []string
+	for path := range m.files {
+		if isBelow(path, dir) {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (m *Memory) Exists(path string) bool {
+	for existing := range m.files {
+		if isBelow(existing, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Memory) Read(path string) ([]byte, error) {
+	data, ok := m.files[path]
+	if !ok {
+		return nil, &os.PathError{Op: "read", Path: path, Err: os.ErrNotExist}
+	}
+
+	return append([]byte(nil), data...), nil
+}
+
+func (m *Memory) Write(path string, data []byte) error {
+	m.files[path] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *Memory) Delete(path string) error {
+	for existing := range m.files {
+		if isBelow(existing, path) {
+			delete(m.files, existing)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) Rename(oldPath, newPath string) error {
+	if !m.Exists(oldPath) {
+		return &os.PathError{Op: "rename", Path: oldPath, Err: os.ErrNotExist}
+	}
+
+	if oldPath == newPath {
+		return nil
+	}
+
+	moved := make(map[string][]byte)
+	for existing, data := range m.files {
+		if isBelow(existing, oldPath) {
+			moved[newPath+strings.TrimPrefix(existing, oldPath)] = data
+			delete(m.files, existing)
+		}
+	}
+
+	for path, data := range moved {
+		m.files[path] = data
+	}
+
+	return nil
+}
+
+func (m *Memory) hasChanges() bool {
+	last := make(map[string][]byte)
+	if len(m.Commits) > 0 {
+		last = m.Commits[len(m.Commits)-1].Files
+	}
+
+	if len(last) != len(m.files) {
+		return true
+	}
+
+	for path, data := range m.files {
+		if lastData, ok := last[path]; !ok || !bytes.Equal(data, lastData) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Memory) Commit(message string) error {
+	if !m.hasChanges() {
+		return ErrNoChanges
+	}
+
+	snapshot := make(map[string][]byte, len(m.files))
+	for path, data := range m.files {
+		snapshot[path] = data
+	}
+
+	m.Commits = append(m.Commits, Commit{Message: message, Date: time.Now(), Files: snapshot})
+	return nil
+}
+
+func (m *Memory) WithTransaction(fn func() error) error {
+	files := make(map[string][]byte, len(m.files))
+	for path, data := range m.files {
+		files[path] = data
+	}
+
+	if err := fn(); err != nil {
+		m.files = files
+		return err
+	}
+
+	return nil
+}
+
+// Log uses the index of a commit in Commits as its hash.
+func (m *Memory) Log(path string) ([]util.LogEntry, error) {
+	entries := []util.LogEntry{}
+	for idx := len(m.Commits) - 1; idx >= 0; idx-- {
+		commit := m.Commits[idx]
+		if path != "" {
+			before := []byte(nil)
+			existedBefore := false
+			if idx > 0 {
+				before, existedBefore = m.Commits[idx-1].Files[path]
+			}
+
+			after, existsAfter := commit.Files[path]
+			if existedBefore == existsAfter && bytes.Equal(before, after) {
+				continue
+			}
+		}
+
+		entries = append(entries, util.LogEntry{
+			Hash:    strconv.Itoa(idx),
+			Date:    commit.Date,
+			Message: commit.Message,
+		})
+	}
+
+	return entries, nil
+}
+
+func (m *Memory) Modified() (map[string]time.Time, error) {
+	modified := make(map[string]time.Time)
+	before := map[string][]byte{}
+	for _, commit := range m.Commits {
+		for path, data := range commit.Files {
+			if lastData, ok := before[path]; !ok || !bytes.Equal(data, lastData) {
+				modified[path] = commit.Date
+			}
+		}
+
+		for path := range before {
+			if _, ok := commit.Files[path]; !ok {
+				modified[path] = commit.Date
+			}
+		}
+
+		before = commit.Files
+	}
+
+	return modified, nil
+}
+
+func (m *Memory) ReadAt(rev, path string) ([]byte, error) {
+	idx, err := strconv.Atoi(rev)
+	if err != nil || idx < 0 || idx >= len(m.Commits) {
+		return nil, fmt.Errorf("Unknown revision '%s'", rev)
+	}
+
+	data, ok := m.Commits[idx].Files[path]
+	if !ok {
+		return nil, &os.PathError{Op: "read", Path: path, Err: os.ErrNotExist}
+	}
+
+	return append([]byte(nil), data...), nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/serztle/nom/util"
+)
+
+// ErrNoChanges is returned by Commit when nothing was modified since the
+// last commit.
+var ErrNoChanges = errors.New("No changes to commit")
+
+// Store is the place where the index, recipes and images live.
+// All paths are relative to the root of the store and use forward slashes.
+type Store interface {
+	// List returns all files below `dir` (use "" for everything), sorted.
+	List(dir string) ([]string, error)
+
+	// Exists tells if there is a file or directory at `path`.
+	Exists(path string) bool
+
+	// Read returns the content of the file at `path`.
+	Read(path string) ([]byte, error)
+
+	// Write replaces the content of the file at `path`, creating parents.
+	Write(path string, data []byte) error
+
+	// Delete removes the file or directory at `path`.
+	Delete(path string) error
+
+	// Rename moves the file or directory at `oldPath` to `newPath`.
+	Rename(oldPath, newPath string) error
+
+	// Commit records all modifications since the last commit.
+	// ErrNoChanges is returned if there was nothing to record.
+	Commit(message string) error
+
+	// WithTransaction runs `fn`. If it fails, every modification done
+	// through the store in the meantime is undone and the error returned.
+	WithTransaction(fn func() error) error
+}
+
+// History is implemented by stores that remember older versions of files.
+type History interface {
+	// Log returns all commits that touched `path`, newest first.
+	// An empty `path` returns all commits.
+	Log(path string) ([]util.LogEntry, error)
+
+	// ReadAt returns the content of `path` as it was at revision `rev`.
+	ReadAt(rev, path string) ([]byte, error)
+
+	// Modified returns for every path ever committed when it was last changed.
+	Modified() (map[string]time.Time, error)
+}
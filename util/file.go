@@ -5,8 +5,70 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
+const (
+	// FilePerm is used for every file written to a repository.
+	FilePerm os.FileMode = 0600
+
+	// DirPerm is used for every directory created in a repository.
+	DirPerm os.FileMode = 0700
+)
+
+// WriteFile writes `data` to `path` atomically: The data goes to a
+// temporary file in the same directory which is synced and then renamed
+// over `path`. On error the previous content of `path` is left untouched.
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmpfile, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpName := tmpfile.Name()
+	success := false
+	defer func() {
+		if !success {
+			tmpfile.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := tmpfile.Write(data); err != nil {
+		return err
+	}
+
+	if err := tmpfile.Chmod(FilePerm); err != nil {
+		return err
+	}
+
+	if err := tmpfile.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmpfile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	success = true
+	syncDir(dir)
+	return nil
+}
+
+// syncDir makes sure a rename inside `dir` hits the disk.
+// Not every platform supports this, so it is done on a best effort basis.
+func syncDir(dir string) {
+	if fd, err := os.Open(dir); err == nil {
+		fd.Sync()
+		fd.Close()
+	}
+}
+
 // CopyFile copies the data from `src` to `dst`.
 func CopyFile(src string, dest string) error {
 	data, err := ioutil.ReadFile(src)
@@ -14,7 +76,7 @@ func CopyFile(src string, dest string) error {
 		return fmt.Errorf("CopyFile: ReadFile: %v", err)
 	}
 
-	if err := ioutil.WriteFile(dest, data, 0600); err != nil {
+	if err := WriteFile(dest, data); err != nil {
 		return fmt.Errorf("CopyFile: WriteFile: %v", err)
 	}
 
@@ -34,11 +96,28 @@ func Edit(filename string) error {
 	return cmd.Run()
 }
 
-// GuardExists returns an descriptive error for the user if `path` exists already.
-func GuardExists(path string) error {
-	if _, err := os.Stat(path); err == nil {
-		return fmt.Errorf("Guard: Destination file already exists (%s). Use --force to ignore this", path)
+// EditData writes `data` to a temporary file, opens it in $EDITOR and
+// returns the edited content. The temporary file is removed afterwards.
+func EditData(data []byte) ([]byte, error) {
+	tmpfile, err := ioutil.TempFile(os.TempDir(), "nom")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write(data); err != nil {
+		tmpfile.Close()
+		return nil, err
 	}
 
-	return nil
+	if err := tmpfile.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := Edit(tmpfile.Name()); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(tmpfile.Name())
 }
@@ -1,20 +1,35 @@
 package util
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type Git struct {
 	Dir string
+
+	// Author overrides the author of new commits ("Name <mail>") if set.
+	Author string
+}
+
+// LogEntry is a single commit as reported by Git.Log.
+type LogEntry struct {
+	Hash    string
+	Date    time.Time
+	Message string
 }
 
 func NewGit(dir string) Git {
 	return Git{Dir: dir}
 }
 
-func (g *Git) Exec(command string, args ...string) error {
+func (g *Git) command(command string, args ...string) *exec.Cmd {
 	repackArgs := []string{
 		"-C", g.Dir,
 		fmt.Sprintf("--git-dir=%s", ".git"),
@@ -22,27 +37,178 @@ func (g *Git) Exec(command string, args ...string) error {
 		command,
 	}
 	repackArgs = append(repackArgs, args...)
-	if err := exec.Command("git", repackArgs...).Run(); err != nil {
+	return exec.Command("git", repackArgs...)
+}
+
+func (g *Git) Exec(command string, args ...string) error {
+	if err := g.command(command, args...).Run(); err != nil {
 		return fmt.Errorf("Git command '%s' failed in '%s' (%v)", command, g.Dir, err)
 	}
 
 	return nil
 }
 
-func (g *Git) WithTransaction(fn func() error) {
-	if err := fn(); err != nil {
-		if errReset := g.Exec("reset"); errReset != nil {
-			fmt.Printf(
-				"Error: Git reset failed (%v). Something went horribly wrong!\nCause: %v. Abort.\n",
-				errReset,
-				err,
-			)
-			os.Exit(1)
-		} else {
-			fmt.Printf("Error: %v. Abort.\n", err)
-			os.Exit(1)
+// Output is like Exec, but returns what git printed on stdout.
+func (g *Git) Output(command string, args ...string) (string, error) {
+	stderr := bytes.Buffer{}
+	cmd := g.command(command, args...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf(
+			"Git command '%s' failed in '%s' (%v: %s)",
+			command, g.Dir, err, strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return string(output), nil
+}
+
+// TransactionError is returned by WithTransaction if `fn` failed.
+// Rollback is set if restoring the previous state failed as well.
+type TransactionError struct {
+	Cause    error
+	Rollback error
+}
+
+func (e *TransactionError) Error() string {
+	if e.Rollback != nil {
+		return fmt.Sprintf(
+			"Error: Rollback failed (%v). Something went horribly wrong!\nCause: %v. Abort.",
+			e.Rollback,
+			e.Cause,
+		)
+	}
+
+	return fmt.Sprintf("Error: %v. Abort.", e.Cause)
+}
+
+// snapshot is the state of a path before a transaction touched it.
+// `files` maps file paths below it (or itself) to their content.
+type snapshot struct {
+	path   string
+	exists bool
+	files  map[string][]byte
+	modes  map[string]os.FileMode
+}
+
+// Transaction remembers the state of all paths it was told about,
+// so they can be restored if something goes wrong.
+type Transaction struct {
+	git       *Git
+	snapshots []*snapshot
+}
+
+func (t *Transaction) covered(path string) bool {
+	for _, snap := range t.snapshots {
+		if snap.path == path || strings.HasPrefix(path, snap.path+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Touch has to be called before `path` (relative to the repository) is
+// modified, removed or created.
+func (t *Transaction) Touch(path string) error {
+	path = filepath.Clean(path)
+	if t.covered(path) {
+		return nil
+	}
+
+	snap := &snapshot{
+		path:  path,
+		files: make(map[string][]byte),
+		modes: make(map[string]os.FileMode),
+	}
+
+	absPath := filepath.Join(t.git.Dir, path)
+	if _, err := os.Lstat(absPath); os.IsNotExist(err) {
+		// Remember the topmost missing directory, so it gets cleaned up too.
+		for parent := filepath.Dir(path); parent != "."; parent = filepath.Dir(parent) {
+			if _, err := os.Lstat(filepath.Join(t.git.Dir, parent)); !os.IsNotExist(err) {
+				break
+			}
+
+			snap.path = parent
+		}
+
+		if !t.covered(snap.path) {
+			t.snapshots = append(t.snapshots, snap)
+		}
+
+		return nil
+	}
+
+	snap.exists = true
+	err := filepath.Walk(absPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		snap.files[filePath] = data
+		snap.modes[filePath] = info.Mode().Perm()
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("Remembering '%s' failed (%v)", path, err)
+	}
+
+	t.snapshots = append(t.snapshots, snap)
+	return nil
+}
+
+// Rollback restores all touched paths and removes them from the git index.
+func (t *Transaction) Rollback() error {
+	paths := []string{}
+	for idx := len(t.snapshots) - 1; idx >= 0; idx-- {
+		snap := t.snapshots[idx]
+		paths = append(paths, snap.path)
+
+		if err := os.RemoveAll(filepath.Join(t.git.Dir, snap.path)); err != nil {
+			return err
+		}
+
+		for filePath, data := range snap.files {
+			if err := os.MkdirAll(filepath.Dir(filePath), DirPerm); err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(filePath, data, snap.modes[filePath]); err != nil {
+				return err
+			}
 		}
 	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	args := append([]string{"-q", "--"}, paths...)
+	if t.git.RefExists("HEAD") {
+		return t.git.Exec("reset", args...)
+	}
+
+	return t.git.Exec("rm", append([]string{"-r", "--cached", "--ignore-unmatch"}, args...)...)
+}
+
+// WithTransaction runs `fn` and restores everything touched through the
+// passed transaction if `fn` fails. The error is then a *TransactionError.
+func (g *Git) WithTransaction(fn func(tx *Transaction) error) error {
+	tx := &Transaction{git: g}
+	if err := fn(tx); err != nil {
+		return &TransactionError{Cause: err, Rollback: tx.Rollback()}
+	}
+
+	return nil
 }
 
 func (g *Git) Exists() bool {
@@ -85,5 +251,158 @@ func (g *Git) Rm(filename string) error {
 }
 
 func (g *Git) Commit(message string) error {
+	if g.Author != "" {
+		author := g.Author
+		if !strings.Contains(author, "<") {
+			author += " <>"
+		}
+
+		return g.Exec("commit", "--author", author, "-m", message)
+	}
+
 	return g.Exec("commit", "-m", message)
 }
+
+// Log returns all commits touching `path` (or all commits if `path` is empty),
+// newest first.
+func (g *Git) Log(path string) ([]LogEntry, error) {
+	args := []string{"--format=%H%x00%aI%x00%s"}
+	if path != "" {
+		args = append(args, "--", path)
+	}
+
+	output, err := g.Output("log", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []LogEntry{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, LogEntry{Hash: parts[0], Date: date, Message: parts[2]})
+	}
+
+	return entries, nil
+}
+
+// Modified returns for every path in the history when it was last changed,
+// reading the whole log once.
+func (g *Git) Modified() (map[string]time.Time, error) {
+	// -z keeps paths unquoted, even with umlauts in them.
+	output, err := g.Output("log", "-z", "--format=%x01%aI", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+
+	modified := make(map[string]time.Time)
+	var date time.Time
+	for _, field := range strings.Split(output, "\x00") {
+		field = strings.TrimPrefix(field, "\n")
+		if strings.HasPrefix(field, "\x01") {
+			if date, err = time.Parse(time.RFC3339, field[1:]); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		// Newer commits come first.
+		if _, ok := modified[field]; field != "" && !ok {
+			modified[field] = date
+		}
+	}
+
+	return modified, nil
+}
+
+// ShowStage returns `path` from the given merge stage:
+// 1 is the common ancestor, 2 is ours and 3 is theirs.
+func (g *Git) ShowStage(stage int, path string) ([]byte, error) {
+	output, err := g.Output("show", fmt.Sprintf(":%d:%s", stage, path))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(output), nil
+}
+
+// Conflicts returns all paths that are unmerged after a failed merge.
+func (g *Git) Conflicts() ([]string, error) {
+	output, err := g.Output("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(output), nil
+}
+
+func (g *Git) CurrentBranch() (string, error) {
+	output, err := g.Output("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// RefExists tells if `ref` can be resolved to a commit.
+func (g *Git) RefExists(ref string) bool {
+	return g.Exec("rev-parse", "--verify", "--quiet", ref+"^{commit}") == nil
+}
+
+func (g *Git) Config(key, value string) error {
+	return g.Exec("config", key, value)
+}
+
+func (g *Git) RemoteAdd(name, url string) error {
+	return g.Exec("remote", "add", name, url)
+}
+
+func (g *Git) Fetch(remote string) error {
+	return g.Exec("fetch", remote)
+}
+
+// Merge merges `ref` into the work tree and index without committing,
+// unless it is a fast-forward. An error usually means conflicts.
+func (g *Git) Merge(ref string) error {
+	return g.Exec("merge", "--no-edit", "--no-commit", ref)
+}
+
+func (g *Git) Push(remote, branch string) error {
+	return g.Exec("push", "--set-upstream", remote, branch)
+}
+
+// Message returns the full commit message of `rev`.
+func (g *Git) Message(rev string) (string, error) {
+	return g.Output("log", "-1", "--format=%B", rev)
+}
+
+// IsPushed tells if `rev` is part of any remote tracking branch.
+func (g *Git) IsPushed(rev string) bool {
+	output, err := g.Output("branch", "--remotes", "--contains", rev)
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// Revert applies the inverse of `rev` to the work tree and index without committing.
+func (g *Git) Revert(rev string) error {
+	return g.Exec("revert", "--no-commit", rev)
+}
+
+// Show returns the content of `path` at revision `rev`.
+func (g *Git) Show(rev, path string) ([]byte, error) {
+	output, err := g.Output("show", fmt.Sprintf("%s:%s", rev, path))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(output), nil
+}
@@ -0,0 +1,253 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	_ "golang.org/x/image/webp"
+)
+
+// CheckImage fails if `data` is plainly no image, like a web page or a
+// text file. Formats nom can't decode, like HEIC, are let through.
+func CheckImage(data []byte) error {
+	if kind := http.DetectContentType(data); strings.HasPrefix(kind, "text/") {
+		return fmt.Errorf("Looks like %s, not like an image", kind)
+	}
+
+	return nil
+}
+
+// NormalizeImage turns the image `data` upright according to its EXIF
+// orientation, shrinks it so no edge is longer than `maxSize` pixels (0
+// keeps the size) and encodes it again as `format` ("jpeg", "png", "webp"
+// or "" for the format it had; gifs become png). WebP is always written
+// lossless. Encoding again drops all metadata, GPS included.
+// It returns the new data and the file extension belonging to it.
+func NormalizeImage(data []byte, maxSize int, format string, quality int) ([]byte, string, error) {
+	img, decoded, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not decode image (%v)", err)
+	}
+
+	if format == "" {
+		format = decoded
+	}
+
+	if decoded == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	img = shrink(img, maxSize)
+
+	buf := &bytes.Buffer{}
+	switch format {
+	case "jpeg", "jpg":
+		if quality <= 0 || quality > 100 {
+			quality = jpeg.DefaultQuality
+		}
+
+		err = jpeg.Encode(buf, flatten(img), &jpeg.Options{Quality: quality})
+		return buf.Bytes(), ".jpg", err
+	case "png", "gif":
+		err = png.Encode(buf, img)
+		return buf.Bytes(), ".png", err
+	case "webp":
+		err = encodeWebP(buf, img)
+		return buf.Bytes(), ".webp", err
+	default:
+		return nil, "", fmt.Errorf("Can't encode images as '%s'; use jpeg, png or webp", format)
+	}
+}
+
+// encodeWebP writes `img` as lossless WebP. The encoder panics on some
+// images (like a single pixel), which is turned into an error here.
+func encodeWebP(w io.Writer, img image.Image) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Could not encode image as WebP (%v)", r)
+		}
+	}()
+
+	return nativewebp.Encode(w, img, nil)
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a jpeg, 1 if unknown.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || pos+2+length > len(data) {
+			// Start of scan: no more metadata follows.
+			return 1
+		}
+
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+
+		pos += 2 + length
+	}
+
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of `tiff`.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for idx := 0; idx < entries; idx++ {
+		entry := ifd + 2 + idx*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// orient rotates and mirrors `img` so that it is upright for the EXIF `orientation`.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			dx, dy := x, y
+			switch orientation {
+			case 2:
+				dx = width - 1 - x
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dy = height - 1 - y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = width-1-y, x
+			case 7:
+				dx, dy = width-1-y, height-1-x
+			case 8:
+				dx, dy = y, height-1-x
+			}
+
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+// shrink scales `img` down so no edge is longer than `maxSize`, averaging
+// all source pixels that fall onto one target pixel.
+func shrink(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return img
+	}
+
+	newWidth, newHeight := maxSize, height*maxSize/width
+	if height > width {
+		newWidth, newHeight = width*maxSize/height, maxSize
+	}
+
+	if newWidth < 1 {
+		newWidth = 1
+	}
+	if newHeight < 1 {
+		newHeight = 1
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		y0, y1 := y*height/newHeight, (y+1)*height/newHeight
+		for x := 0; x < newWidth; x++ {
+			x0, x1 := x*width/newWidth, (x+1)*width/newWidth
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := src.NRGBAAt(sx, sy)
+					r, g, b, a = r+int(pixel.R), g+int(pixel.G), b+int(pixel.B), a+int(pixel.A)
+					n++
+				}
+			}
+
+			if n > 0 {
+				dst.SetNRGBA(x, y, color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+			}
+		}
+	}
+
+	return dst
+}
+
+// flatten puts `img` on a white background, as jpeg has no transparency.
+func flatten(img image.Image) image.Image {
+	if _, ok := img.(*image.YCbCr); ok {
+		return img
+	}
+
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.ZP, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
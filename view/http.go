@@ -11,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/serztle/nom/index"
-	"github.com/serztle/nom/util"
 )
 
 const baseTemplate = `
@@ -127,22 +126,30 @@ h2 {
 
 const indexTemplate = `
 {{define "body"}}
+{{if .Collections}}
+<div class="desc">
+    <a class="seamless" href="{{$.RootRel}}index.html">All</a>
+    {{range .Collections}}
+    &middot; <a class="seamless" href="{{$.RootRel}}collection/{{.}}.html">{{.}}</a>
+    {{end}}
+</div>
+{{end}}
 <div class="gallery">
 {{range .Recipes}}
     <div class="col">
         <center>
 		<div class="cover">
-			{{if (ge (len .Data.Images) 1) }}
-				<a target="_blank" href="detail/{{.Name}}.html">
-				  <img class="image" src="{{index .Data.Images 0}}" alt="{{.Data.Name}}">
+			{{if .CoverImage}}
+				<a target="_blank" href="{{$.RootRel}}detail/{{.Name}}.html">
+				  <img class="image" src="{{$.RootRel}}{{.CoverImage}}" alt="{{.Data.Name}}">
 				</a>
 			{{else}}
-				<a target="_blank" href="detail/{{.Name}}.html">
+				<a target="_blank" href="{{$.RootRel}}detail/{{.Name}}.html">
 					<img class="image">
 				</a>
 			{{end}}
 		</div>
-		<a class="seamless" href="detail/{{.Name}}.html">
+		<a class="seamless" href="{{$.RootRel}}detail/{{.Name}}.html">
 			<div class="desc">{{.Data.Name}}</div>
 		</a>
         </center>
@@ -184,14 +191,20 @@ const detailTemplate = `
     <div class="recipe">
         <h2>Recipe</h2>{{template "section" .Recipe.Data.Recipe}}
     </div>
+    {{if .Recipe.Data.Notes}}
+    <div class="notes">
+        <h2>Notes</h2>{{template "section" .Recipe.Data.Notes}}
+    </div>
+    {{end}}
 
     <div>
         <h2>Images</h2>
         {{range .Recipe.Data.Images}}
         <div>
             <a target="_blank" href="{{$.RootRel}}{{.}}">
-                <img class="image" src="{{$.RootRel}}{{.}}" alt="{{$.Recipe.Data.Name}}" width="200" height="100">
+                <img class="image" src="{{$.RootRel}}{{.}}" alt="{{or ($.Recipe.Caption .) $.Recipe.Data.Name}}" width="200" height="100">
             </a>
+            {{with $.Recipe.Caption .}}<div class="desc">{{.}}</div>{{end}}
         </div>
         {{end}}
     </div>
@@ -218,14 +231,27 @@ func withTemplate(name, tmplTxt string, fn func() (interface{}, error)) (*bytes.
 	return &html, nil
 }
 
-func renderIndex(store *index.Index) (*bytes.Buffer, error) {
+func renderIndex(store *index.Index, collection string) (*bytes.Buffer, error) {
 	return withTemplate("index", indexTemplate, func() (interface{}, error) {
+		collections := index.NewCollections(store.Store())
+		if err := collections.Parse(); err != nil {
+			return nil, err
+		}
+
+		store, title, rootRel := store.Active(), "Overview", ""
+		if collection != "" {
+			filtered, err := collections.Filter(store, collection)
+			if err != nil {
+				return nil, err
+			}
+
+			store, title, rootRel = filtered, collection, "../"
+		}
+
 		recipes := []index.Recipe{}
 		for recipeName := range store.Recipes {
-			recipe := index.Recipe{}
-			recipePath := filepath.Join(store.RepoDir(), recipeName)
-			recipe.Name = recipeName
-			if err := recipe.Parse(recipePath); err != nil {
+			recipe := index.NewRecipe(recipeName)
+			if err := recipe.Load(store.Store()); err != nil {
 				return nil, err
 			}
 
@@ -233,17 +259,21 @@ func renderIndex(store *index.Index) (*bytes.Buffer, error) {
 		}
 
 		return struct {
-			Title   string
-			Recipes []index.Recipe
+			Title       string
+			RootRel     string
+			Collections []string
+			Recipes     []index.Recipe
 		}{
-			Title:   "Overview",
-			Recipes: recipes,
+			Title:       title,
+			RootRel:     rootRel,
+			Collections: collections.Names(),
+			Recipes:     recipes,
 		}, nil
 	})
 }
 
 func indexHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (int, error) {
-	html, err := renderIndex(store)
+	html, err := renderIndex(store, "")
 	if err != nil {
 		return 500, err
 	}
@@ -251,22 +281,32 @@ func indexHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (i
 	return w.Write(html.Bytes())
 }
 
+func collectionHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (int, error) {
+	collection := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/collection/"), ".html")
+
+	html, err := renderIndex(store, collection)
+	if err != nil {
+		return http.StatusNotFound, err
+	}
+
+	return w.Write(html.Bytes())
+}
+
 func renderDetail(store *index.Index, recipeName string) (*bytes.Buffer, error) {
 	return withTemplate("detail", detailTemplate, func() (interface{}, error) {
-		recipe := index.NewRecipe(store.RepoDir(), recipeName)
-		recipe.Name = recipeName
-		if err := recipe.Load(); err != nil {
+		recipe := index.NewRecipe(recipeName)
+		if err := recipe.Load(store.Store()); err != nil {
 			return nil, err
 		}
 
 		return struct {
 			Title   string
 			RootRel string
-			Recipe  index.Recipe
+			Recipe  *index.Recipe
 		}{
 			Title:   recipe.Data.Name,
 			RootRel: strings.Repeat("../", strings.Count(recipeName, fmt.Sprintf("%c", os.PathSeparator))+1),
-			Recipe:  recipe,
+			Recipe:  &recipe,
 		}, nil
 	})
 }
@@ -285,9 +325,9 @@ func detailHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (
 
 func imageHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (int, error) {
 	// TODO: Might crash a bit harder.
-	imagePath := filepath.Join(store.RepoDir(), r.RequestURI[1:])
+	imagePath := r.RequestURI[1:]
 
-	data, err := ioutil.ReadFile(imagePath)
+	data, err := store.Store().Read(imagePath)
 	if err != nil {
 		return 500, fmt.Errorf("Error: reading image %s (%v)", imagePath, err)
 	}
@@ -297,7 +337,7 @@ func imageHandler(store *index.Index, w http.ResponseWriter, r *http.Request) (i
 
 func renderStatic(store *index.Index, staticDir string) error {
 	dir := filepath.Clean(staticDir)
-	indexPage, err := renderIndex(store)
+	indexPage, err := renderIndex(store, "")
 	if err != nil {
 		return err
 	}
@@ -311,6 +351,27 @@ func renderStatic(store *index.Index, staticDir string) error {
 		return err
 	}
 
+	collections := index.NewCollections(store.Store())
+	if err := collections.Parse(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "collection"), 0700); err != nil {
+		return err
+	}
+
+	for _, collection := range collections.Names() {
+		collectionPage, err := renderIndex(store, collection)
+		if err != nil {
+			return err
+		}
+
+		collectionPath := filepath.Join(dir, "collection", collection+".html")
+		if err := ioutil.WriteFile(collectionPath, collectionPage.Bytes(), 0600); err != nil {
+			return err
+		}
+	}
+
 	if err := os.MkdirAll(filepath.Join(dir, "detail"), 0700); err != nil {
 		return err
 	}
@@ -330,24 +391,28 @@ func renderStatic(store *index.Index, staticDir string) error {
 		}
 	}
 
-	imagePath := filepath.Join(store.RepoDir(), ".images")
-	return filepath.Walk(imagePath, func(path string, info os.FileInfo, err error) error {
-		relPath, err := filepath.Rel(store.RepoDir(), path)
+	imagePaths, err := store.Store().List(".images")
+	if err != nil {
+		return err
+	}
+
+	for _, imagePath := range imagePaths {
+		data, err := store.Store().Read(imagePath)
 		if err != nil {
 			return err
 		}
-		destPath := filepath.Join(dir, relPath)
-		err = os.MkdirAll(filepath.Dir(destPath), 0700)
-		if err != nil {
+
+		destPath := filepath.Join(dir, filepath.FromSlash(imagePath))
+		if err := os.MkdirAll(filepath.Dir(destPath), 0700); err != nil {
 			return err
 		}
 
-		if info.Mode().IsRegular() {
-			return util.CopyFile(path, destPath)
+		if err := ioutil.WriteFile(destPath, data, 0600); err != nil {
+			return err
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
 
 type httpHandler struct {
@@ -383,6 +448,7 @@ func Serve(store *index.Index, staticDir string) error {
 
 	fmt.Println("Visit http://localhost:8080")
 	http.Handle("/", httpHandler{store, indexHandler})
+	http.Handle("/collection/", httpHandler{store, collectionHandler})
 	http.Handle("/detail/", httpHandler{store, detailHandler})
 	http.Handle("/.images/", httpHandler{store, imageHandler})
 	return http.ListenAndServe(":8080", nil)